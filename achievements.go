@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	achievementsFile = "achievements.json"
+	toastFrames      = 4 * 60
+	dietTarget       = 10
+	tunnelVisionSize = 40
+	frenzyScore      = 10000
+	frenzySpeed      = 2
+	sharkLungeFrames = 60
+)
+
+// Achievement descriptions are translated on screen; Goal fills in the description's %d, if it has one.
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+	Goal        int
+}
+
+var achievementList = []Achievement{
+	{"balanced-diet", "Balanced diet", "Eat %d of each species", dietTarget},
+	{"flatland", "Flatland", "Win with a single game plane", 0},
+	{"tunnel-vision", "Tunnel vision", "Reach size %d without ever switching planes", tunnelVisionSize},
+	{"gourmet", "Gourmet", "Eat a puffed-up puffer", 0},
+	{"close-shave", "Close shave", "Survive a shark lunge", 0},
+	{"feeding-frenzy", "Feeding frenzy", "Score %d with frenzy fish speed", frenzyScore},
+}
+
+// AchievementProgress is what survives between sessions.
+type AchievementProgress struct {
+	Unlocked map[string]time.Time `json:"unlocked"`
+	Eaten    map[string]int       `json:"eaten"`
+}
+
+type sharkLunge struct {
+	player *PlayerFish
+	frames int
+}
+
+type Achievements struct {
+	AchievementProgress
+	toasts        []string
+	toastTimer    float64
+	switchedPlane [maxPlayers]bool
+	lunges        []sharkLunge
+}
+
+func (g *Game) LoadAchievements() {
+	g.achievements.AchievementProgress = AchievementProgress{Unlocked: map[string]time.Time{}, Eaten: map[string]int{}}
+	if err := loadConfig(achievementsFile, &g.achievements.AchievementProgress); err != nil {
+		log.Printf("achievements: %v", err)
+	}
+}
+
+func (a *Achievements) Save() {
+	if err := saveConfig(achievementsFile, a.AchievementProgress); err != nil {
+		log.Printf("achievements: %v", err)
+	}
+}
+
+// Handle checks the event against every achievement; autopilot and headless runs never count.
+func (a *Achievements) Handle(g *Game, event GameEvent) {
+	if !g.TracksProgress(event.Player) {
+		return
+	}
+	switch event.Kind {
+	case eventStart:
+		a.switchedPlane = [maxPlayers]bool{}
+		a.lunges = a.lunges[:0]
+		a.Save()
+	case eventTick:
+		for i := range g.players {
+			player := &g.players[i]
+			if g.TracksProgress(player) && !player.Dead && g.planeCount > 1 && !a.switchedPlane[player.Index] && player.Size >= tunnelVisionSize {
+				a.Unlock("tunnel-vision")
+			}
+		}
+		lunges := a.lunges[:0]
+		for _, lunge := range a.lunges {
+			if lunge.frames--; lunge.frames > 0 {
+				lunges = append(lunges, lunge)
+			} else if !lunge.player.Dead {
+				a.Unlock("close-shave")
+			}
+		}
+		a.lunges = lunges
+	case eventEat:
+		a.Eaten[event.Fish.Type]++
+		if a.HasBalancedDiet() {
+			a.Unlock("balanced-diet")
+		}
+		if slices.ContainsFunc(event.Bonuses, func(bonus ScoreBonus) bool { return bonus.Label == "PUFFED!" }) {
+			a.Unlock("gourmet")
+		}
+		if g.fishSpeedModifier == frenzySpeed && g.score >= frenzyScore {
+			a.Unlock("feeding-frenzy")
+		}
+	case eventDeath:
+		a.lunges = slices.DeleteFunc(a.lunges, func(lunge sharkLunge) bool { return lunge.player == event.Player })
+	case eventPlaneSwitch:
+		a.switchedPlane[event.Player.Index] = true
+	case eventLunge:
+		a.lunges = append(a.lunges, sharkLunge{player: event.Player, frames: sharkLungeFrames})
+	case eventGameOver, eventLeave:
+		a.Save()
+	case eventVictory:
+		if g.planeCount == 1 {
+			a.Unlock("flatland")
+		}
+		a.Save()
+	}
+}
+
+func (a *Achievements) HasBalancedDiet() bool {
+	for _, species := range behaviours {
+		if a.Eaten[species] < dietTarget {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Achievements) Unlock(id string) {
+	if _, ok := a.Unlocked[id]; ok {
+		return
+	}
+	a.Unlocked[id] = time.Now()
+	for _, achievement := range achievementList {
+		if achievement.ID == id {
+			a.toasts = append(a.toasts, achievement.Name)
+		}
+	}
+	a.Save()
+}
+
+func (a *Achievements) ToastTick() {
+	if len(a.toasts) == 0 {
+		return
+	}
+	if a.toastTimer++; a.toastTimer >= toastFrames {
+		a.toasts, a.toastTimer = a.toasts[1:], 0
+	}
+}
+
+// DrawToast slides the oldest unlock in from the top right corner and keeps the rest waiting.
+func (g *Game) DrawToast() {
+	a := &g.achievements
+	if len(a.toasts) == 0 {
+		return
+	}
+	toast := g.tr("ACHIEVEMENT: %s", strings.ToUpper(g.tr(a.toasts[0])))
+	face := g.GetFontFace("small", false)
+	width, height := text.Measure(toast, face, 0)
+	slide := min(1, a.toastTimer/20, (toastFrames-a.toastTimer)/20)
+	x, y := g.screenWidth-width-0.02*g.screenWidth, -height+slide*(height+0.02*g.screenHeight)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x, y)
+	op.ColorScale.ScaleWithColor(color.RGBA{255, 200, 0, 255})
+	text.Draw(g.screen, toast, face, op)
+}
+
+func (g *Game) DrawAchievements() {
+	g.DrawAllNpcFish()
+	a := &g.achievements
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.1*g.screenWidth, 0.05*g.screenHeight)
+	text.Draw(g.screen, g.tr("ACHIEVEMENTS  %d/%d", len(a.Unlocked), len(achievementList)), g.GetFontFace("medium", true), op)
+	face := g.GetFontFace("small", false)
+	for i, achievement := range achievementList {
+		status := a.AchievementStatus(g, achievement)
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(0.1*g.screenWidth, (0.18+0.11*float64(i))*g.screenHeight)
+		if _, ok := a.Unlocked[achievement.ID]; ok {
+			op.ColorScale.ScaleWithColor(color.RGBA{255, 200, 0, 255})
+		} else {
+			op.ColorScale.ScaleAlpha(0.6)
+		}
+		text.Draw(g.screen, g.tr(achievement.Name)+" - "+achievement.Describe(g), face, op)
+		op.GeoM.Translate(0.03*g.screenWidth, 1.2*g.Font("small"))
+		text.Draw(g.screen, status, face, op)
+	}
+	op = &text.DrawOptions{}
+	op.GeoM.Translate(0.1*g.screenWidth, 0.9*g.screenHeight)
+	text.Draw(g.screen, g.tr("Back"), g.GetFontFace("biggish", true), op)
+}
+
+func (achievement Achievement) Describe(g *Game) string {
+	if achievement.Goal == 0 {
+		return g.tr(achievement.Description)
+	}
+	return g.tr(achievement.Description, achievement.Goal)
+}
+
+func (a *Achievements) AchievementStatus(g *Game, achievement Achievement) string {
+	if unlocked, ok := a.Unlocked[achievement.ID]; ok {
+		return g.tr("Unlocked %s", unlocked.Format("2006-01-02"))
+	}
+	if achievement.ID != "balanced-diet" {
+		return g.tr("Locked")
+	}
+	progress := []string{}
+	for _, species := range behaviours {
+		progress = append(progress, fmt.Sprintf("%s %d/%d", g.tr(species), min(a.Eaten[species], dietTarget), dietTarget))
+	}
+	return strings.Join(progress, ", ")
+}
+
+func (g *Game) AchievementsCycle() error {
+	for i := range g.fish {
+		g.fish[i].Move()
+	}
+	if g.IsActionPressed(actionBack, true) || g.IsActionPressed(actionConfirm, true) {
+		g.GoToMenu(false)
+	}
+	return nil
+}
+
+func (g *Game) GoToAchievements() {
+	g.gameState = gameAchievements
+}
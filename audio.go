@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+const (
+	audioSampleRate = 44100
+	musicVolume     = 0.35
+	backPlaneVolume = 0.45
+	muffleCutoff    = 0.12
+)
+
+// Sound holds every synth preset rendered up front, plus a muffled copy for events on the back plane.
+// Headless games have no Sound at all.
+type Sound struct {
+	context   *audio.Context
+	clips     map[string][]byte
+	muffled   map[string][]byte
+	playing   []*audio.Player
+	music     Music
+	volume    float64
+	muted     bool
+	menuState int
+	menuIndex int
+}
+
+func (g *Game) LoadSound() {
+	s := &Sound{context: audio.NewContext(audioSampleRate), clips: map[string][]byte{}, muffled: map[string][]byte{}, volume: 1}
+	presets := loadSynthPresets()
+	g.ApplyModSynth(presets)
+	for name, preset := range presets {
+		clip := pcmStereo(Synthesize(preset, audioSampleRate))
+		s.clips[name], s.muffled[name] = clip, muffle(clip)
+	}
+	g.ApplyModSounds(s)
+	s.StartMusic()
+	g.sound = s
+}
+
+func (s *Sound) SetVolume(volume float64, muted bool) {
+	s.volume, s.muted = volume, muted
+}
+
+// Play starts an effect; pitch above 1 plays it higher and shorter. Sounds from the back plane are quieter and muffled.
+func (s *Sound) Play(name string, plane, pitch float64) {
+	if s.muted {
+		return
+	}
+	clip, volume := s.clips[name], s.volume
+	if plane > 0 {
+		clip, volume = s.muffled[name], volume*backPlaneVolume
+	}
+	if pitch != 1 {
+		clip = repitch(clip, pitch)
+	}
+	playing := s.playing[:0]
+	for _, player := range s.playing {
+		if player.IsPlaying() {
+			playing = append(playing, player)
+		} else {
+			player.Close()
+		}
+	}
+	player := s.context.NewPlayerFromBytes(clip)
+	player.SetVolume(volume)
+	player.Play()
+	s.playing = append(playing, player)
+}
+
+func (s *Sound) Handle(event GameEvent) {
+	s.HandleMusic(event)
+	switch event.Kind {
+	case eventEat:
+		s.Play("gulp", event.Player.Plane, math.Max(0.6, math.Min(1.8, math.Sqrt(20/event.Fish.Size))))
+	case eventDeath:
+		s.Play("sting", event.Player.Plane, 1)
+	case eventPlaneSwitch:
+		s.Play("whoosh", event.Player.Plane, 1)
+	case eventPuff:
+		s.Play("bubble", event.Fish.Plane, 1)
+	case eventLunge:
+		s.Play("lunge", event.Fish.Plane, 1)
+	case eventVictory:
+		s.Play("fanfare", 0, 1)
+	}
+}
+
+// MenuTick clicks when the highlighted menu item moves and chimes when a menu leads to another screen.
+func (s *Sound) MenuTick(g *Game) {
+	inMenu := func(state int) bool {
+		return state == gameMenu || state == gameOptionsMenu || state == gameControlsMenu || state == gameLobby
+	}
+	switch {
+	case g.gameState != s.menuState && inMenu(s.menuState):
+		s.Play("chime", 0, 1)
+	case g.gameState == s.menuState && inMenu(g.gameState) && g.activeMenuIndex != s.menuIndex:
+		s.Play("click", 0, 1)
+	}
+	s.menuState, s.menuIndex = g.gameState, g.activeMenuIndex
+}
+
+// repitch resamples 16-bit stereo frames, which changes pitch and length together.
+func repitch(clip []byte, pitch float64) []byte {
+	frames := len(clip) / 4
+	out := make([]byte, 4*int(float64(frames)/pitch))
+	for i := 0; i < len(out)/4; i++ {
+		copy(out[4*i:4*i+4], clip[4*int(float64(i)*pitch):])
+	}
+	return out
+}
+
+// muffle runs a one-pole low-pass filter over both channels, so the back plane sounds like it is under more water.
+func muffle(clip []byte) []byte {
+	out := make([]byte, len(clip))
+	var left, right float64
+	for i := 0; i+4 <= len(clip); i += 4 {
+		left += muffleCutoff * (float64(int16(binary.LittleEndian.Uint16(clip[i:]))) - left)
+		right += muffleCutoff * (float64(int16(binary.LittleEndian.Uint16(clip[i+2:]))) - right)
+		binary.LittleEndian.PutUint16(out[i:], uint16(int16(left)))
+		binary.LittleEndian.PutUint16(out[i+2:], uint16(int16(right)))
+	}
+	return out
+}
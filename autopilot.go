@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	attractModeDelay   = 20 * 60
+	autopilotPlaneWait = 60
+)
+
+// Autopilot chases the most rewarding edible fish nearby and steers away from anything bigger.
+type Autopilot struct {
+	planeCooldown int
+}
+
+type BenchmarkResult struct {
+	Runs      int
+	Victories int
+	Score     float64
+	Eaten     float64
+	Ticks     float64
+	Elapsed   time.Duration
+}
+
+func (bot *Autopilot) Decide(fish *PlayerFish, others []Fish) (frame InputFrame) {
+	if bot.planeCooldown > 0 {
+		bot.planeCooldown--
+	}
+	reach := fish.HalfWidth + fish.HalfHeight
+	var fleeX, fleeY, bestValue float64
+	var target *Fish
+	danger, otherPlaneDanger, otherPlaneFood := 0.0, 0.0, false
+	for i := range others {
+		other := &others[i]
+		if other.Dead {
+			continue
+		}
+		dx, dy := other.X-fish.X, other.Y-fish.Y
+		distance := math.Max(math.Hypot(dx, dy), 1)
+		threatRange := 3 * (reach + other.HalfWidth)
+		bigger := other.Size > fish.Size
+		if other.Plane != fish.Plane {
+			if bigger && distance < threatRange {
+				otherPlaneDanger += threatRange / distance
+			}
+			if !bigger && distance < threatRange {
+				otherPlaneFood = true
+			}
+			continue
+		}
+		if bigger {
+			if distance < threatRange {
+				weight := threatRange / distance
+				fleeX, fleeY = fleeX-dx/distance*weight, fleeY-dy/distance*weight
+				danger += weight
+			}
+			continue
+		}
+		if value := other.Size / distance; value > bestValue {
+			bestValue, target = value, other
+		}
+	}
+	var chaseX, chaseY float64
+	if target != nil {
+		leadX, leadY := target.X+target.SpeedX*10, target.Y+target.SpeedY*10
+		dx, dy := leadX-fish.X, leadY-fish.Y
+		distance := math.Max(math.Hypot(dx, dy), 1)
+		chaseX, chaseY = dx/distance, dy/distance
+		frame.Dash = distance < 4*reach && danger == 0
+	}
+	wallX, wallY := bot.avoidWalls(fish)
+	frame.DriveX = chaseX + 2*fleeX + wallX
+	frame.DriveY = chaseY + 2*fleeY + wallY
+	if bot.planeCooldown == 0 && fish.game.planeCount > 1 {
+		escape := danger > 2 && otherPlaneDanger < danger/2
+		hunt := target == nil && otherPlaneFood && otherPlaneDanger == 0
+		if escape || hunt {
+			frame.Plane = true
+			bot.planeCooldown = autopilotPlaneWait
+		}
+	}
+	return
+}
+
+func (bot *Autopilot) avoidWalls(fish *PlayerFish) (x, y float64) {
+	margin := 2 * fish.HalfWidth
+	switch {
+	case fish.X < margin:
+		x = 1
+	case fish.X > fish.game.worldWidth-margin:
+		x = -1
+	}
+	switch {
+	case fish.Y < margin:
+		y = 1
+	case fish.Y > fish.game.worldHeight-margin:
+		y = -1
+	}
+	return
+}
+
+func (g *Game) DrawDemoBanner() {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.42*g.screenWidth, 0.05*g.screenHeight)
+	op.ColorScale.ScaleAlpha(float32(0.6 + 0.4*math.Sin(float64(g.idleTicks)/20)))
+	text.Draw(g.screen, g.tr("DEMO"), g.GetFontFace("big", true), op)
+}
+
+func (g *Game) IsAnyInput() bool {
+	if x, y := ebiten.CursorPosition(); x != g.prevCurX || y != g.prevCurY {
+		return true
+	}
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		return true
+	}
+	for _, button := range []ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonMiddle, ebiten.MouseButtonRight} {
+		if inpututil.IsMouseButtonJustPressed(button) {
+			return true
+		}
+	}
+	for _, id := range g.gamepadIds {
+		if len(inpututil.AppendJustPressedGamepadButtons(id, nil)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) StartDemo() {
+	g.demo = true
+	g.Start()
+	for i := range g.players {
+		g.players[i].Input = &AgentInput{Policy: &Autopilot{}}
+	}
+}
+
+func (g *Game) StopDemo() {
+	g.LeaveDemo()
+	g.GoToMenu(true)
+}
+
+// LeaveDemo hands the players back from the autopilot to their devices.
+func (g *Game) LeaveDemo() {
+	g.demo = false
+	g.idleTicks = 0
+	for i := range g.players {
+		g.players[i].Input = &DeviceInput{}
+	}
+}
+
+// RunBenchmark plays the given number of headless autopilot runs as fast as possible, each capped at maxTicks.
+func RunBenchmark(runs, maxTicks int, options map[int]float64) (result BenchmarkResult, err error) {
+	g := NewHeadlessGame()
+	for option, value := range options {
+		if err = g.SetOption(option, value); err != nil {
+			return
+		}
+	}
+	g.ApplyOptions()
+	started := time.Now()
+	for run := 0; run < runs; run++ {
+		g.Start()
+		for i := range g.players {
+			g.players[i].Input = &AgentInput{Policy: &Autopilot{}}
+		}
+		ticks := 0
+		for ; ticks < maxTicks && g.gameState == gameRunning; ticks++ {
+			if err = g.Update(); err != nil {
+				return
+			}
+		}
+		result.Runs++
+		result.Score += g.score
+		result.Eaten += g.eaten
+		result.Ticks += float64(ticks)
+		if g.gameState == gameVictory {
+			result.Victories++
+		}
+	}
+	result.Elapsed = time.Since(started)
+	return
+}
+
+func (result BenchmarkResult) String() string {
+	runs := math.Max(float64(result.Runs), 1)
+	return fmt.Sprintf("runs: %d\nvictories: %d (%0.1f%%)\naverage score: %0.0f\naverage fish eaten: %0.1f\naverage survival: %0.1fs\nsimulation speed: %0.0f ticks/s",
+		result.Runs, result.Victories, 100*float64(result.Victories)/runs, result.Score/runs, result.Eaten/runs, result.Ticks/runs/60, result.Ticks/result.Elapsed.Seconds())
+}
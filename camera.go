@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	cameraFollow   = 0.08
+	cameraZoomRate = 0.02
+	cameraMargin   = 0.25
+	// playerViewShare is the widest a player may look before the camera starts zooming out.
+	playerViewShare = 0.125
+)
+
+// Camera looks at X, Y in the world. It moves with the simulation, not with the frame rate,
+// so fish spawn around the same view on every peer of an online game.
+type Camera struct {
+	X, Y float64
+	Zoom float64
+}
+
+// SetOceanSize makes the world the given number of views wide and high; one view is the classic fixed ocean.
+func (g *Game) SetOceanSize(screens float64) {
+	g.oceanSize = screens
+	g.worldWidth, g.worldHeight = screens*g.viewWidth, screens*g.viewHeight
+	g.ResetCamera()
+}
+
+// CameraTarget frames every live player, zoomed out far enough that the biggest of them keeps some room around it.
+func (g *Game) CameraTarget() (x, y, zoom float64) {
+	x, y, zoom = g.worldWidth/2, g.worldHeight/2, 1
+	if g.gameState != gameRunning && g.gameState != gameVictory {
+		return
+	}
+	left, top, right, bottom := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i := range g.players {
+		player := &g.players[i]
+		if player.Dead && !g.AllPlayersDead() {
+			continue
+		}
+		left, right = math.Min(left, player.X-player.HalfWidth), math.Max(right, player.X+player.HalfWidth)
+		top, bottom = math.Min(top, player.Y-player.HalfHeight), math.Max(bottom, player.Y+player.HalfHeight)
+		zoom = math.Min(zoom, playerViewShare*g.viewWidth/(2*player.HalfWidth))
+	}
+	if len(g.players) == 0 {
+		return
+	}
+	x, y = (left+right)/2, (top+bottom)/2
+	zoom = math.Min(zoom, math.Min(g.viewWidth/(right-left)/(1+cameraMargin), g.viewHeight/(bottom-top)/(1+cameraMargin)))
+	return
+}
+
+// ClampCamera keeps the view inside the world; a world the size of the screen pins the camera in place.
+func (g *Game) ClampCamera() {
+	c := &g.camera
+	c.Zoom = math.Max(c.Zoom, math.Max(g.viewWidth/g.worldWidth, g.viewHeight/g.worldHeight))
+	halfWidth, halfHeight := g.viewWidth/c.Zoom/2, g.viewHeight/c.Zoom/2
+	c.X = math.Max(halfWidth, math.Min(g.worldWidth-halfWidth, c.X))
+	c.Y = math.Max(halfHeight, math.Min(g.worldHeight-halfHeight, c.Y))
+}
+
+func (g *Game) ResetCamera() {
+	g.camera.X, g.camera.Y, g.camera.Zoom = g.CameraTarget()
+	g.ClampCamera()
+}
+
+// CameraTick eases the camera towards its target, so it neither jerks after a dash nor pops when a player grows.
+func (g *Game) CameraTick() {
+	x, y, zoom := g.CameraTarget()
+	c := &g.camera
+	c.X += cameraFollow * (x - c.X)
+	c.Y += cameraFollow * (y - c.Y)
+	c.Zoom += cameraZoomRate * (zoom - c.Zoom)
+	g.ClampCamera()
+}
+
+// View is the part of the world on screen.
+func (g *Game) View() (left, top, right, bottom float64) {
+	c := &g.camera
+	halfWidth, halfHeight := g.viewWidth/c.Zoom/2, g.viewHeight/c.Zoom/2
+	return c.X - halfWidth, c.Y - halfHeight, c.X + halfWidth, c.Y + halfHeight
+}
+
+// SpawnArea is the view with a margin around it, as far as the world reaches. Fish are born at its edges and
+// recycled once they leave it, so the ocean is only ever populated around the players.
+func (g *Game) SpawnArea() (left, top, right, bottom float64) {
+	left, top, right, bottom = g.View()
+	marginX, marginY := cameraMargin*(right-left), cameraMargin*(bottom-top)
+	return math.Max(0, left-marginX), math.Max(0, top-marginY), math.Min(g.worldWidth, right+marginX), math.Min(g.worldHeight, bottom+marginY)
+}
+
+// PixelScale is how many screen pixels one world unit takes up.
+func (g *Game) PixelScale() float64 {
+	return g.camera.Zoom * g.screenHeight / g.viewHeight
+}
+
+// CameraGeoM takes world coordinates to screen pixels.
+func (g *Game) CameraGeoM() ebiten.GeoM {
+	var geoM ebiten.GeoM
+	geoM.Translate(-g.camera.X, -g.camera.Y)
+	geoM.Scale(g.PixelScale(), g.PixelScale())
+	geoM.Translate(g.screenWidth/2, g.screenHeight/2)
+	return geoM
+}
+
+// ToScreen is for labels and popups, which follow the fish around but keep their size.
+func (g *Game) ToScreen(x, y float64) (float64, float64) {
+	return (x-g.camera.X)*g.PixelScale() + g.screenWidth/2, (y-g.camera.Y)*g.PixelScale() + g.screenHeight/2
+}
+
+// ToWorld is the other way round, for steering with the mouse.
+func (g *Game) ToWorld(x, y float64) (float64, float64) {
+	return (x-g.screenWidth/2)/g.PixelScale() + g.camera.X, (y-g.screenHeight/2)/g.PixelScale() + g.camera.Y
+}
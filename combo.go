@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	comboWindow      = 3 * 60
+	comboDecayWindow = 60
+	comboStep        = 0.25
+	popupFrames      = 75
+	recordsFile      = "records.json"
+)
+
+type ScoreBonus struct {
+	Label  string
+	Factor float64
+}
+
+// Records are the personal bests that survive between sessions.
+type Records struct {
+	BestCombo float64 `json:"best_combo"`
+}
+
+type ScorePopup struct {
+	X, Y  float64
+	Lines []string
+	Life  float64
+}
+
+func (fish *PlayerFish) ComboMultiplier() float64 {
+	return comboMultiplier(fish.Combo)
+}
+
+// comboMultiplier is what a combo of the given length multiplies the points by; the HUD shows records with it too.
+func comboMultiplier(combo float64) float64 {
+	return 1 + comboStep*math.Max(combo-1, 0)
+}
+
+// ComboTick lets the multiplier run out one step at a time instead of dropping to zero at once.
+func (fish *PlayerFish) ComboTick() {
+	if fish.ComboTimer == 0 {
+		return
+	}
+	fish.ComboTimer--
+	if fish.ComboTimer == 0 && fish.Combo > 0 {
+		fish.Combo--
+		if fish.Combo > 0 {
+			fish.ComboTimer = comboDecayWindow
+		}
+	}
+}
+
+func (g *Game) LoadRecords() {
+	var records Records
+	if err := loadConfig(recordsFile, &records); err != nil {
+		log.Printf("records: %v", err)
+	}
+	g.bestCombo = records.BestCombo
+}
+
+// HandleRecords saves the records whenever a tracked run ends, however it ends.
+func (g *Game) HandleRecords(event GameEvent) {
+	if !g.TracksProgress(nil) {
+		return
+	}
+	switch event.Kind {
+	case eventGameOver, eventVictory, eventLeave:
+		if err := saveConfig(recordsFile, Records{BestCombo: g.bestCombo}); err != nil {
+			log.Printf("records: %v", err)
+		}
+	}
+}
+
+func (g *Game) AddPopup(x, y float64, lines ...string) {
+	g.popups = append(g.popups, ScorePopup{X: x, Y: y, Lines: lines, Life: popupFrames})
+}
+
+func (g *Game) AverageFishSize() float64 {
+	var total, count float64
+	for i := range g.fish {
+		if !g.fish[i].Dead {
+			total += g.fish[i].Size
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
+
+func (g *Game) DrawPopups() {
+	face := g.GetFontFace("small", true)
+	for _, popup := range g.popups {
+		progress := 1 - popup.Life/popupFrames
+		for i, line := range popup.Lines {
+			op := &text.DrawOptions{}
+			width, _ := text.Measure(line, face, 0)
+			x, y := g.ToScreen(popup.X, popup.Y)
+			op.GeoM.Translate(x-width/2, y-progress*0.05*g.screenHeight+float64(i)*g.Font("small"))
+			op.ColorScale.ScaleWithColor(popupColor(i))
+			op.ColorScale.ScaleAlpha(float32(math.Min(1, 2*(1-progress))))
+			text.Draw(g.screen, line, face, op)
+		}
+	}
+}
+
+// GetPreyBonuses has to be called before the target dies, since dying resets the puffer's inflation cooldown.
+func (g *Game) GetPreyBonuses(eater *PlayerFish, target *Fish) (bonuses []ScoreBonus) {
+	if average := g.AverageFishSize(); average > 0 && target.Size > average {
+		bonuses = append(bonuses, ScoreBonus{"BIG CATCH", 0.5})
+	}
+	if target.Type == "puffer" && target.Cooldown > 60*15 {
+		bonuses = append(bonuses, ScoreBonus{"PUFFED!", 1})
+	}
+	if target.Type == "shark" && target.Size >= eater.Size*0.85 {
+		bonuses = append(bonuses, ScoreBonus{"SHARK BITE!", 1.5})
+	}
+	return
+}
+
+func (g *Game) PopupsTick() {
+	alive := g.popups[:0]
+	for _, popup := range g.popups {
+		popup.Life--
+		if popup.Life > 0 {
+			alive = append(alive, popup)
+		}
+	}
+	g.popups = alive
+}
+
+func (g *Game) ScorePopupLines(eater *PlayerFish, points float64, bonuses []ScoreBonus) (lines []string) {
+	lines = append(lines, fmt.Sprintf("+%0.0f", points))
+	if eater.Combo > 1 {
+		lines = append(lines, g.tr("x%0.2f COMBO", eater.ComboMultiplier()))
+	}
+	for _, bonus := range bonuses {
+		lines = append(lines, g.tr(bonus.Label))
+	}
+	return
+}
+
+func popupColor(line int) color.Color {
+	if line == 0 {
+		return color.White
+	}
+	return color.RGBA{255, 200, 0, 255}
+}
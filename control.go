@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+)
+
+const controlTimeout = 2 * time.Second
+
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// optionNames are the names the control API uses for everything ApplyOptions handles.
+var optionNames = map[string]int{
+	"planes":            optionPlanes,
+	"fish_amount":       optionFishAmount,
+	"fish_speed":        optionFishSpeed,
+	"fish_size":         optionFishSize,
+	"fish_reactions":    optionFishReactions,
+	"ocean_size":        optionOceanSize,
+	"fullscreen":        optionFullscreen,
+	"display":           optionDisplay,
+	"players":           optionPlayers,
+	"player_collisions": optionPlayerCollisions,
+	"language":          optionLanguage,
+	"volume":            optionVolume,
+	"mute":              optionMute,
+}
+
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type SpawnParams struct {
+	Species string   `json:"species"`
+	Size    float64  `json:"size"`
+	Plane   float64  `json:"plane"`
+	X       *float64 `json:"x"`
+	Y       *float64 `json:"y"`
+}
+
+type OptionParams struct {
+	Option string  `json:"option"`
+	Value  float64 `json:"value"`
+}
+
+// ControlState is what game.state returns: the spectator snapshot plus the bits only a controller cares about.
+type ControlState struct {
+	GameSnapshot
+	Paused  bool               `json:"paused"`
+	Online  bool               `json:"online"`
+	Options map[string]float64 `json:"options"`
+}
+
+// controlCall carries one request to the game loop, which answers on reply once it has run it.
+type controlCall struct {
+	request RPCRequest
+	reply   chan RPCResponse
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// ServeControl listens for JSON-RPC 2.0 requests POSTed to /rpc. Bind it to localhost: anyone who can reach it can drive the game.
+// Requests have to be application/json and come from no page or this one, since a browser sends a cross-site
+// text/plain POST to localhost without asking first.
+func (g *Game) ServeControl(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	g.controlCalls = make(chan controlCall, 16)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST a JSON-RPC request", http.StatusMethodNotAllowed)
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "the request must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		response := RPCResponse{JSONRPC: "2.0"}
+		var request RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.Error = &RPCError{rpcParseError, err.Error()}
+		} else {
+			response = g.callControl(request)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+	log.Printf("control API on http://%s/rpc", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("control server: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (g *Game) callControl(request RPCRequest) RPCResponse {
+	call := controlCall{request: request, reply: make(chan RPCResponse, 1)}
+	timeout := time.After(controlTimeout)
+	select {
+	case g.controlCalls <- call:
+	case <-timeout:
+		return RPCResponse{JSONRPC: "2.0", Error: &RPCError{rpcServerError, "the game is not responding"}, ID: request.ID}
+	}
+	select {
+	case response := <-call.reply:
+		return response
+	case <-timeout:
+		return RPCResponse{JSONRPC: "2.0", Error: &RPCError{rpcServerError, "the game is not responding"}, ID: request.ID}
+	}
+}
+
+// HandleControlCalls runs queued requests between ticks, so they never race the simulation.
+func (g *Game) HandleControlCalls() {
+	for {
+		select {
+		case call := <-g.controlCalls:
+			response := RPCResponse{JSONRPC: "2.0", ID: call.request.ID}
+			result, err := g.RunControl(call.request.Method, call.request.Params)
+			var rpcErr *RPCError
+			switch {
+			case errors.As(err, &rpcErr):
+				response.Error = rpcErr
+			case err != nil:
+				response.Error = &RPCError{rpcServerError, err.Error()}
+			default:
+				response.Result = result
+			}
+			call.reply <- response
+		default:
+			return
+		}
+	}
+}
+
+func (g *Game) RunControl(method string, params json.RawMessage) (any, error) {
+	if g.net != nil && method != "game.state" && method != "options.get" {
+		return nil, errors.New("the game is online; only queries are allowed")
+	}
+	switch method {
+	case "game.state":
+		return g.ControlState(), nil
+	case "game.pause", "game.resume":
+		if g.gameState != gameRunning {
+			return nil, errors.New("no game is running")
+		}
+		g.paused = method == "game.pause"
+		return g.paused, nil
+	case "game.menu":
+		if g.demo {
+			g.StopDemo()
+		} else {
+			g.GoToMenu(true)
+		}
+		return true, nil
+	case "game.restart":
+		if g.demo {
+			g.LeaveDemo()
+		}
+		g.Start()
+		return true, nil
+	case "options.get":
+		return g.ControlState().Options, nil
+	case "options.set":
+		var p OptionParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error()}
+		}
+		option, ok := optionNames[p.Option]
+		if !ok {
+			return nil, &RPCError{rpcInvalidParams, fmt.Sprintf("unknown option %q", p.Option)}
+		}
+		if err := g.SetOption(option, p.Value); err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error()}
+		}
+		g.ApplyOptions()
+		return g.ControlState().Options, nil
+	case "fish.spawn":
+		var p SpawnParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error()}
+		}
+		fish, err := g.SpawnFish(p)
+		if err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error()}
+		}
+		return snapshotFish(fish), nil
+	}
+	return nil, &RPCError{rpcMethodNotFound, fmt.Sprintf("unknown method %q", method)}
+}
+
+func (g *Game) ControlState() ControlState {
+	state := ControlState{GameSnapshot: g.Snapshot(0), Paused: g.paused, Online: g.net != nil, Options: map[string]float64{}}
+	for name, option := range optionNames {
+		state.Options[name] = g.optionsMenu[option].GetValue()
+	}
+	return state
+}
+
+// SpawnFish adds a fish to the ocean, swimming in from a random edge unless a position is given.
+// Once it leaves the screen it respawns like any other fish, until a new game or an option change regenerates the ocean.
+func (g *Game) SpawnFish(p SpawnParams) (*Fish, error) {
+	species, known := g.FindSpecies(p.Species)
+	switch {
+	case !known:
+		return nil, fmt.Errorf("unknown species %q, expected one of %v", p.Species, g.SpeciesNames())
+	case p.Size < 1:
+		return nil, errors.New("size must be at least 1")
+	case p.Plane < 0 || p.Plane >= g.planeCount || p.Plane != float64(int(p.Plane)):
+		return nil, fmt.Errorf("plane must be a whole number below %v", g.planeCount)
+	case g.totalFishCount >= len(g.fishStaticArray):
+		spawned := g.totalFishCount - int(g.fishPerPlane*g.planeCount)
+		return nil, fmt.Errorf("the ocean is full: it holds %d fish, %d of them spawned, and only a new game or an option change clears them", g.totalFishCount, spawned)
+	}
+	g.totalFishCount++
+	g.fish = g.fishStaticArray[0:g.totalFishCount]
+	fish := &g.fish[g.totalFishCount-1]
+	fish.Init(g, species)
+	fish.Randomize()
+	fish.Plane = p.Plane
+	fish.SetSize(p.Size)
+	left, top, right, bottom := g.SpawnArea()
+	switch {
+	case fish.Type == "jelly" && fish.SpeedY < 0:
+		fish.Y = bottom + fish.HalfHeight - 1
+	case fish.Type == "jelly":
+		fish.Y = top + 1 - fish.HalfHeight
+	case fish.SpeedX < 0:
+		fish.X = right + fish.HalfWidth - 1
+	default:
+		fish.X = left + 1 - fish.HalfWidth
+	}
+	if p.X != nil {
+		fish.X = *p.X
+	}
+	if p.Y != nil {
+		fish.Y = *p.Y
+	}
+	return fish, nil
+}
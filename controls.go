@@ -0,0 +1,414 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	actionUp = iota
+	actionDown
+	actionLeft
+	actionRight
+	actionPlane
+	actionDash
+	actionPause
+	actionBack
+	actionConfirm
+	actionHideMenu
+	actionCount
+)
+
+const (
+	contextGame = 1 << iota
+	contextMenu
+)
+
+const controlsFile = "controls.json"
+
+type Action struct {
+	Name    string
+	Context int
+}
+
+type Binding struct {
+	Keys    []ebiten.Key
+	Mouse   []ebiten.MouseButton
+	Gamepad []ebiten.StandardGamepadButton
+}
+
+type Bindings [actionCount]Binding
+
+// requiredActions can't be left without a binding: every menu, mouse clicks and "Reset to defaults" included, needs them.
+var requiredActions = []int{actionConfirm, actionBack}
+
+// clearInputs empty the binding under the cursor on the Controls screen, unless the player has bound them to an action.
+var clearInputs = []Binding{
+	{Keys: []ebiten.Key{ebiten.KeyDelete}},
+	{Keys: []ebiten.Key{ebiten.KeyBackspace}},
+	{Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightTop}},
+}
+
+var (
+	actions = [actionCount]Action{
+		actionUp:       {"Move up", contextGame | contextMenu},
+		actionDown:     {"Move down", contextGame | contextMenu},
+		actionLeft:     {"Move left", contextGame | contextMenu},
+		actionRight:    {"Move right", contextGame | contextMenu},
+		actionPlane:    {"Switch plane", contextGame},
+		actionDash:     {"Dash", contextGame},
+		actionPause:    {"Pause", contextGame},
+		actionBack:     {"Back", contextGame | contextMenu},
+		actionConfirm:  {"Confirm", contextMenu},
+		actionHideMenu: {"Hide menu", contextMenu},
+	}
+	mouseButtonNames = map[ebiten.MouseButton]string{
+		ebiten.MouseButtonLeft:   "LMB",
+		ebiten.MouseButtonMiddle: "MMB",
+		ebiten.MouseButtonRight:  "RMB",
+	}
+	gamepadButtonNames = map[ebiten.StandardGamepadButton]string{
+		ebiten.StandardGamepadButtonRightBottom:      "Pad A",
+		ebiten.StandardGamepadButtonRightRight:       "Pad B",
+		ebiten.StandardGamepadButtonRightLeft:        "Pad X",
+		ebiten.StandardGamepadButtonRightTop:         "Pad Y",
+		ebiten.StandardGamepadButtonFrontTopLeft:     "Pad LB",
+		ebiten.StandardGamepadButtonFrontTopRight:    "Pad RB",
+		ebiten.StandardGamepadButtonFrontBottomLeft:  "Pad LT",
+		ebiten.StandardGamepadButtonFrontBottomRight: "Pad RT",
+		ebiten.StandardGamepadButtonCenterLeft:       "Pad Back",
+		ebiten.StandardGamepadButtonCenterRight:      "Pad Start",
+		ebiten.StandardGamepadButtonLeftStick:        "Pad LS",
+		ebiten.StandardGamepadButtonRightStick:       "Pad RS",
+		ebiten.StandardGamepadButtonLeftTop:          "D-pad up",
+		ebiten.StandardGamepadButtonLeftBottom:       "D-pad down",
+		ebiten.StandardGamepadButtonLeftLeft:         "D-pad left",
+		ebiten.StandardGamepadButtonLeftRight:        "D-pad right",
+	}
+	leftKeyboard = Bindings{
+		actionUp:    {Keys: []ebiten.Key{ebiten.KeyW}},
+		actionDown:  {Keys: []ebiten.Key{ebiten.KeyS}},
+		actionLeft:  {Keys: []ebiten.Key{ebiten.KeyA}},
+		actionRight: {Keys: []ebiten.Key{ebiten.KeyD}},
+		actionPlane: {Keys: []ebiten.Key{ebiten.KeySpace}},
+		actionDash:  {Keys: []ebiten.Key{ebiten.KeyShiftLeft}},
+	}
+	rightKeyboard = Bindings{
+		actionUp:    {Keys: []ebiten.Key{ebiten.KeyArrowUp}},
+		actionDown:  {Keys: []ebiten.Key{ebiten.KeyArrowDown}},
+		actionLeft:  {Keys: []ebiten.Key{ebiten.KeyArrowLeft}},
+		actionRight: {Keys: []ebiten.Key{ebiten.KeyArrowRight}},
+		actionPlane: {Keys: []ebiten.Key{ebiten.KeyControlRight, ebiten.KeyNumpad0}},
+		actionDash:  {Keys: []ebiten.Key{ebiten.KeyShiftRight}},
+	}
+)
+
+func defaultBindings() Bindings {
+	return Bindings{
+		actionUp: {
+			Keys:    []ebiten.Key{ebiten.KeyW, ebiten.KeyArrowUp},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftTop},
+		},
+		actionDown: {
+			Keys:    []ebiten.Key{ebiten.KeyS, ebiten.KeyArrowDown},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftBottom},
+		},
+		actionLeft: {
+			Keys:    []ebiten.Key{ebiten.KeyA, ebiten.KeyArrowLeft},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftLeft},
+		},
+		actionRight: {
+			Keys:    []ebiten.Key{ebiten.KeyD, ebiten.KeyArrowRight},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftRight},
+		},
+		actionPlane: {
+			Keys:    []ebiten.Key{ebiten.KeySpace},
+			Mouse:   []ebiten.MouseButton{ebiten.MouseButtonRight},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightBottom},
+		},
+		actionDash: {
+			Keys:    []ebiten.Key{ebiten.KeyShift},
+			Mouse:   []ebiten.MouseButton{ebiten.MouseButtonMiddle},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightLeft},
+		},
+		actionPause: {
+			Keys:    []ebiten.Key{ebiten.KeyP, ebiten.KeyPause, ebiten.KeyEnter},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonCenterRight},
+		},
+		actionBack: {
+			Keys:    []ebiten.Key{ebiten.KeyEscape},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightRight},
+		},
+		actionConfirm: {
+			Keys:    []ebiten.Key{ebiten.KeyEnter, ebiten.KeySpace},
+			Mouse:   []ebiten.MouseButton{ebiten.MouseButtonLeft},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightBottom, ebiten.StandardGamepadButtonCenterRight},
+		},
+		actionHideMenu: {
+			Keys:    []ebiten.Key{ebiten.KeyH},
+			Gamepad: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonCenterLeft},
+		},
+	}
+}
+
+func (b *Binding) Add(input Binding) {
+	for _, key := range input.Keys {
+		if !slices.Contains(b.Keys, key) {
+			b.Keys = append(b.Keys, key)
+		}
+	}
+	for _, button := range input.Mouse {
+		if !slices.Contains(b.Mouse, button) {
+			b.Mouse = append(b.Mouse, button)
+		}
+	}
+	for _, button := range input.Gamepad {
+		if !slices.Contains(b.Gamepad, button) {
+			b.Gamepad = append(b.Gamepad, button)
+		}
+	}
+}
+
+func (b *Binding) IsEmpty() bool {
+	return len(b.Keys) == 0 && len(b.Mouse) == 0 && len(b.Gamepad) == 0
+}
+
+func (b *Binding) Overlaps(input Binding) bool {
+	for _, key := range input.Keys {
+		if slices.Contains(b.Keys, key) {
+			return true
+		}
+	}
+	for _, button := range input.Mouse {
+		if slices.Contains(b.Mouse, button) {
+			return true
+		}
+	}
+	for _, button := range input.Gamepad {
+		if slices.Contains(b.Gamepad, button) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Binding) String() string {
+	var names []string
+	for _, key := range b.Keys {
+		names = append(names, key.String())
+	}
+	for _, button := range b.Mouse {
+		names = append(names, mouseButtonNames[button])
+	}
+	for _, button := range b.Gamepad {
+		names = append(names, gamepadButtonNames[button])
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func (g *Game) CaptureBinding() (input Binding, captured bool) {
+	if keys := inpututil.AppendJustPressedKeys(nil); len(keys) > 0 {
+		return Binding{Keys: keys[:1]}, true
+	}
+	for button := range mouseButtonNames {
+		if inpututil.IsMouseButtonJustPressed(button) {
+			return Binding{Mouse: []ebiten.MouseButton{button}}, true
+		}
+	}
+	for _, id := range g.gamepadIds {
+		if buttons := inpututil.AppendJustPressedStandardGamepadButtons(id, nil); len(buttons) > 0 {
+			return Binding{Gamepad: buttons[:1]}, true
+		}
+	}
+	return
+}
+
+func (g *Game) ControlsCycle() error {
+	for i, _ := range g.fish {
+		g.fish[i].Move()
+	}
+	resetIndex, backIndex := actionCount, actionCount+1
+	if g.capturingAction >= 0 {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			g.capturingAction = -1
+			g.controlsMessage = ""
+			return nil
+		}
+		input, captured := g.CaptureBinding()
+		if !captured {
+			return nil
+		}
+		if other, conflict := g.FindConflict(g.capturingAction, input); conflict {
+			g.controlsMessage = g.tr("%s is already used by %s", input.String(), g.tr(actions[other].Name))
+			return nil
+		}
+		g.bindings[g.capturingAction].Add(input)
+		g.capturingAction = -1
+		g.controlsMessage = ""
+		g.SaveBindings()
+		return nil
+	}
+	if g.IsActionPressed(actionBack, true) {
+		g.GoToOptions()
+		return nil
+	}
+	if g.MenuButtonDown() {
+		g.activeMenuIndex = int(math.Min(float64(g.activeMenuIndex+1), float64(backIndex)))
+	}
+	if g.MenuButtonUp() {
+		g.activeMenuIndex = int(math.Max(float64(g.activeMenuIndex-1), 0))
+	}
+	if g.HasMouseMoved() {
+		for i, _ := range g.controlsMenu {
+			if g.controlsMenu[i].DetectHover() {
+				g.activeMenuIndex = i
+			}
+		}
+	}
+	if g.activeMenuIndex < actionCount && g.IsClearPressed() {
+		if slices.Contains(requiredActions, g.activeMenuIndex) {
+			g.controlsMessage = g.tr("%s can't be left without a binding", g.tr(actions[g.activeMenuIndex].Name))
+		} else {
+			g.bindings[g.activeMenuIndex] = Binding{}
+			g.SaveBindings()
+		}
+	}
+	if g.IsActionPressed(actionConfirm, true) {
+		switch {
+		case g.activeMenuIndex < actionCount:
+			g.capturingAction = g.activeMenuIndex
+			g.controlsMessage = g.tr("Press a key or button for %s (Esc to cancel)", g.tr(actions[g.activeMenuIndex].Name))
+		case g.activeMenuIndex == resetIndex:
+			g.bindings = defaultBindings()
+			g.SaveBindings()
+		case g.activeMenuIndex == backIndex:
+			g.GoToOptions()
+		}
+	}
+	return nil
+}
+
+func (g *Game) CreateControlsMenu() {
+	for _, action := range actions {
+		g.controlsMenu = append(g.controlsMenu, MenuItem{title: action.Name})
+	}
+	for _, title := range []string{"Reset to defaults", "Back"} {
+		g.controlsMenu = append(g.controlsMenu, MenuItem{title: title})
+	}
+}
+
+func (g *Game) DrawControls() {
+	g.DrawAllNpcFish()
+	for i, item := range g.controlsMenu {
+		if i < actionCount {
+			item.titles = []string{g.bindings[i].String()}
+		}
+		item.Draw(g, i == g.activeMenuIndex)
+	}
+	if g.controlsMessage == "" {
+		return
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.1*g.screenWidth, 0.9*g.screenHeight)
+	op.ColorScale.ScaleWithColor(color.RGBA{255, 200, 0, 255})
+	text.Draw(g.screen, g.controlsMessage, g.GetFontFace("small", false), op)
+}
+
+// IsClearPressed checks the clear inputs that no action uses, so clearing never fires along with an action.
+func (g *Game) IsClearPressed() bool {
+	for _, input := range clearInputs {
+		if slices.ContainsFunc(g.bindings[:], func(binding Binding) bool { return binding.Overlaps(input) }) {
+			continue
+		}
+		if isAnyOfKeysPressed(true, input.Keys...) || g.isAnyGamepadButtonsPressed(true, input.Gamepad...) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindConflict reports another action that already uses the input in a context the action is also active in.
+func (g *Game) FindConflict(action int, input Binding) (int, bool) {
+	for other := range g.bindings {
+		if other != action && actions[other].Context&actions[action].Context != 0 && g.bindings[other].Overlaps(input) {
+			return other, true
+		}
+	}
+	return 0, false
+}
+
+func (g *Game) GoToControls() {
+	g.gameState = gameControlsMenu
+	g.activeMenuIndex = 0
+	g.capturingAction = -1
+	g.controlsMessage = g.tr("Confirm to add a binding, Delete or Pad Y to clear one")
+}
+
+// IsActionPressed checks the action on every device at once, which is what menus want.
+func (g *Game) IsActionPressed(action int, just bool) bool {
+	if g.headless {
+		return false
+	}
+	binding := &g.bindings[action]
+	return isAnyOfKeysPressed(just, binding.Keys...) || isAnyMouseButtonPressed(just, binding.Mouse...) || g.isAnyGamepadButtonsPressed(just, binding.Gamepad...)
+}
+
+func (g *Game) LoadBindings() {
+	g.bindings = defaultBindings()
+	saved := map[string]Binding{}
+	if err := loadConfig(controlsFile, &saved); err != nil {
+		log.Printf("controls: %v", err)
+		return
+	}
+	for i, action := range actions {
+		binding, ok := saved[action.Name]
+		if ok && (!slices.Contains(requiredActions, i) || !binding.IsEmpty()) {
+			g.bindings[i] = binding
+		}
+	}
+}
+
+func (g *Game) SaveBindings() {
+	saved := map[string]Binding{}
+	for i, action := range actions {
+		saved[action.Name] = g.bindings[i]
+	}
+	if err := saveConfig(controlsFile, saved); err != nil {
+		g.controlsMessage = g.tr("Could not save controls: %s", err)
+	}
+}
+
+func (fish *PlayerFish) IsActionPressed(action int, just bool) bool {
+	if keys := fish.Device.Keys; keys != nil && isAnyOfKeysPressed(just, keys[action].Keys...) {
+		return true
+	}
+	binding := &fish.game.bindings[action]
+	if fish.Device.Mouse && isAnyMouseButtonPressed(just, binding.Mouse...) {
+		return true
+	}
+	id, ok := fish.GamepadID()
+	return ok && isGamepadButtonsPressed(id, just, binding.Gamepad...)
+}
+
+func isAnyMouseButtonPressed(just bool, buttons ...ebiten.MouseButton) bool {
+	method := ebiten.IsMouseButtonPressed
+	if just {
+		method = inpututil.IsMouseButtonJustPressed
+	}
+	for _, button := range buttons {
+		if method(button) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// DeathCause is the fish that ate a player, frozen at the moment it happened.
+type DeathCause struct {
+	Type       string
+	Size       float64
+	Plane      float64
+	Lunging    bool
+	FacingLeft bool
+	Mask       image.Image
+	Tint       color.Color
+	image      *ebiten.Image
+}
+
+// IsLunging is true for a shark from the moment it spots its prey until it turns back.
+func (fish *Fish) IsLunging() bool {
+	return fish.Type == "shark" && (fish.Cooldown < 0 || fish.Cooldown > 9*60)
+}
+
+// RecordDeathCause keeps the killer of the last player to die; online only the local player counts.
+func (g *Game) RecordDeathCause(event GameEvent) {
+	if event.Kind != eventStart && (event.Kind != eventDeath || (g.net != nil && event.Player.Index != g.net.local)) {
+		return
+	}
+	if g.deathCause != nil && g.deathCause.image != nil {
+		g.deathCause.image.Dispose()
+	}
+	g.deathCause = nil
+	if event.Kind == eventDeath {
+		killer := event.Fish
+		g.deathCause = &DeathCause{killer.Type, killer.Size, killer.Plane, killer.IsLunging(), killer.FacingLeft, killer.Mask, killer.Tint, nil}
+	}
+}
+
+func (cause *DeathCause) Caption(g *Game) string {
+	caption := g.tr("Eaten by a %s of size %0.0f on the front plane", g.tr(cause.Type), cause.Size)
+	if cause.Plane > 0 {
+		caption = g.tr("Eaten by a %s of size %0.0f on the back plane", g.tr(cause.Type), cause.Size)
+	}
+	if cause.Lunging {
+		caption += g.tr(", mid-lunge")
+	}
+	return caption
+}
+
+// DrawDeathCause shows the killer in a small frame above the quote, facing the way it was swimming.
+func (g *Game) DrawDeathCause() {
+	cause := g.deathCause
+	if cause == nil || cause.Mask == nil {
+		return
+	}
+	if cause.image == nil {
+		cause.image = ebiten.NewImageFromImage(cause.Mask)
+	}
+	boxWidth, boxHeight := 0.16*g.screenWidth, 0.14*g.screenHeight
+	boxX, boxY := 0.5*(g.screenWidth-boxWidth), 0.16*g.screenHeight
+	vector.DrawFilledRect(g.screen, float32(boxX), float32(boxY), float32(boxWidth), float32(boxHeight), color.RGBA{0, 0, 0, 96}, false)
+	vector.StrokeRect(g.screen, float32(boxX), float32(boxY), float32(boxWidth), float32(boxHeight), 1, color.RGBA{255, 255, 255, 96}, false)
+	bounds := cause.image.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	scale := 0.85 * math.Min(boxWidth/width, boxHeight/height)
+	flipX := 1.0
+	if cause.FacingLeft {
+		flipX = -1
+	}
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Translate(-width/2, -height/2)
+	op.GeoM.Scale(scale*flipX, scale)
+	op.GeoM.Translate(boxX+boxWidth/2, boxY+boxHeight/2)
+	if cause.Tint != nil {
+		op.ColorScale.ScaleWithColor(cause.Tint)
+	}
+	g.screen.DrawImage(cause.image, op)
+	face := g.GetFontFace("small", false)
+	caption := cause.Caption(g)
+	captionWidth, _ := text.Measure(caption, face, 0)
+	textOp := &text.DrawOptions{}
+	textOp.GeoM.Translate(0.5*(g.screenWidth-captionWidth), boxY+boxHeight+0.01*g.screenHeight)
+	if cause.Lunging {
+		textOp.ColorScale.ScaleWithColor(color.RGBA{255, 120, 80, 255})
+	}
+	text.Draw(g.screen, caption, face, textOp)
+}
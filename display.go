@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	displayLetterbox = iota
+	displayExtend
+)
+
+// Letterboxed says whether the playfield keeps the classic 16:9 shape. Online games always do,
+// since the playfield decides where fish spawn and every peer has to agree on it.
+func (g *Game) Letterboxed() bool {
+	return g.display == displayLetterbox || g.net != nil
+}
+
+// Layout renders at the real pixel size of the window, device scale factor included, so nothing is upscaled on HiDPI screens.
+// Letterboxing trims it to 16:9 and ebiten fills the rest with bars.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	scale := ebiten.Monitor().DeviceScaleFactor()
+	width, height := float64(outsideWidth)*scale, float64(outsideHeight)*scale
+	if g.Letterboxed() {
+		width, height = math.Min(width, height*screenWidth/screenHeight), math.Min(height, width*screenHeight/screenWidth)
+	}
+	width, height = math.Max(1, math.Round(width)), math.Max(1, math.Round(height))
+	if width != g.screenWidth || height != g.screenHeight {
+		g.Resize(width, height)
+	}
+	return int(width), int(height)
+}
+
+// Resize is called whenever the window changes. The playfield is always screenHeight world units high;
+// extending it only makes it wider or narrower, so fish keep their size whatever the window.
+// A letterboxed playfield never changes, which keeps the camera of an online game out of it.
+// Victory doesn't depend on it; a player who outgrows a narrow ocean is held in the middle until then.
+func (g *Game) Resize(width, height float64) {
+	g.screenWidth, g.screenHeight = width, height
+	g.SetFontsSizes()
+	g.LayoutMenus()
+	viewWidth := float64(screenWidth)
+	if !g.Letterboxed() {
+		viewWidth = screenHeight * width / height
+	}
+	if viewWidth != g.viewWidth {
+		g.viewWidth = viewWidth
+		g.SetOceanSize(g.oceanSize)
+	}
+}
+
+// LayoutMenus places every menu for the current screen size.
+func (g *Game) LayoutMenus() {
+	h := 0.1 * g.screenHeight
+	stackMenu(g.mainMenu, 0.2*g.screenWidth, 0.35*g.screenHeight, h, g.FontFace(math.Min(g.Font("big"), 0.8*h), true))
+	h = math.Min(0.12, 0.85/float64(len(g.optionsMenu))) * g.screenHeight
+	stackMenu(g.optionsMenu, 0.2*g.screenWidth, 0.075*g.screenHeight, h, g.FontFace(math.Min(g.Font("biggish"), 0.8*h), true))
+	stackMenu(g.controlsMenu, 0.1*g.screenWidth, 0.05*g.screenHeight, 0.8*g.screenHeight/float64(actionCount+2), g.GetFontFace("small", true))
+	stackMenu(g.lobbyMenu, 0.2*g.screenWidth, 0.1*g.screenHeight, 0.1*g.screenHeight, g.GetFontFace("biggish", true))
+}
+
+func stackMenu(items []MenuItem, x, y, h float64, face text.Face) {
+	for i := range items {
+		items[i].x, items[i].y, items[i].h, items[i].fontFace = x, y+float64(i)*h, h, face
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"slices"
+)
+
+const (
+	frameChannels       = 4
+	defaultObservedFish = 16
+)
+
+var species = []string{"jelly", "bass", "goldfish", "puffer", "shark", "player"}
+
+// EnvOptions configures an episode; zero values keep the current setting, except a death penalty of 0, which turns it off.
+type EnvOptions struct {
+	Planes       float64  `json:"planes"`
+	FishAmount   float64  `json:"fish_amount"`
+	FishSpeed    float64  `json:"fish_speed"`
+	FishSize     float64  `json:"fish_size"`
+	FrameSkip    int      `json:"frame_skip"`
+	MaxTicks     int      `json:"max_ticks"`
+	DeathPenalty *float64 `json:"death_penalty"`
+	ObservedFish int      `json:"observed_fish"`
+	Frame        bool     `json:"frame"`
+	FrameWidth   int      `json:"frame_width"`
+	FrameHeight  int      `json:"frame_height"`
+}
+
+type EnvAction struct {
+	DriveX float64 `json:"drive_x"`
+	DriveY float64 `json:"drive_y"`
+	Plane  bool    `json:"plane"`
+	Dash   bool    `json:"dash"`
+}
+
+type ObservedFish struct {
+	DX      float64 `json:"dx"`
+	DY      float64 `json:"dy"`
+	Size    float64 `json:"size"`
+	Plane   float64 `json:"plane"`
+	Species int     `json:"species"`
+	Dead    bool    `json:"dead"`
+}
+
+// Observation flattens to Features as [player x, y, speed x, speed y, size, plane, stage] followed by 6 values per nearby fish.
+type Observation struct {
+	Features []float64      `json:"features"`
+	Fish     []ObservedFish `json:"fish"`
+	Frame    []float32      `json:"frame,omitempty"`
+	Shape    []int          `json:"frame_shape,omitempty"`
+	Info     map[string]any `json:"info"`
+}
+
+type EnvRequest struct {
+	Method  string     `json:"method"`
+	Seed    int64      `json:"seed"`
+	Options EnvOptions `json:"options"`
+	Action  EnvAction  `json:"action"`
+}
+
+type EnvResponse struct {
+	Observation *Observation `json:"observation,omitempty"`
+	Reward      float64      `json:"reward"`
+	Done        bool         `json:"done"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// ExternalInput hands the fish whatever action the environment was last given; plane and dash fire once per action.
+type ExternalInput struct {
+	Frame InputFrame
+}
+
+type Environment struct {
+	game      *Game
+	input     *ExternalInput
+	options   EnvOptions
+	lastScore float64
+	ticks     int
+	done      bool
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{game: NewHeadlessGame(), input: &ExternalInput{}}
+}
+
+func (input *ExternalInput) Poll(fish *PlayerFish) InputFrame {
+	frame := input.Frame
+	input.Frame.Plane, input.Frame.Dash = false, false
+	return frame
+}
+
+func (env *Environment) Observe() *Observation {
+	g := env.game
+	player := &g.players[0]
+	obs := &Observation{
+		Features: []float64{player.X / g.worldWidth, player.Y / g.worldHeight, player.SpeedX, player.SpeedY, player.Size, player.Plane, float64(player.Stage)},
+		Info:     map[string]any{"score": g.score, "eaten": g.eaten, "ticks": env.ticks},
+	}
+	nearest := make([]*Fish, 0, len(g.fish))
+	for i := range g.fish {
+		nearest = append(nearest, &g.fish[i])
+	}
+	distance := func(fish *Fish) float64 { return math.Hypot(fish.X-player.X, fish.Y-player.Y) }
+	slices.SortFunc(nearest, func(a, b *Fish) int {
+		switch da, db := distance(a), distance(b); {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	})
+	for i := 0; i < env.options.ObservedFish; i++ {
+		observed := ObservedFish{Species: -1}
+		if i < len(nearest) {
+			fish := nearest[i]
+			observed = ObservedFish{
+				DX:      (fish.X - player.X) / g.viewWidth,
+				DY:      (fish.Y - player.Y) / g.viewHeight,
+				Size:    fish.Size / player.Size,
+				Plane:   fish.Plane - player.Plane,
+				Species: slices.Index(species, fish.Type),
+				Dead:    fish.Dead,
+			}
+			obs.Fish = append(obs.Fish, observed)
+		}
+		dead := 0.0
+		if observed.Dead {
+			dead = 1
+		}
+		obs.Features = append(obs.Features, observed.DX, observed.DY, observed.Size, observed.Plane, float64(observed.Species), dead)
+	}
+	if env.options.Frame {
+		obs.Frame = env.RenderFrame()
+		obs.Shape = []int{frameChannels, env.options.FrameHeight, env.options.FrameWidth}
+	}
+	return obs
+}
+
+// RenderFrame rasterises the camera view into channels for edible fish, threats, fish on other planes and the player itself.
+func (env *Environment) RenderFrame() []float32 {
+	g := env.game
+	width, height := env.options.FrameWidth, env.options.FrameHeight
+	frame := make([]float32, frameChannels*width*height)
+	player := &g.players[0]
+	left, top, right, bottom := g.View()
+	paint := func(fish *Fish, channel int) {
+		cellWidth, cellHeight := (right-left)/float64(width), (bottom-top)/float64(height)
+		x0, x1 := int(math.Floor((fish.X-fish.HalfWidth-left)/cellWidth)), int(math.Floor((fish.X+fish.HalfWidth-left)/cellWidth))
+		y0, y1 := int(math.Floor((fish.Y-fish.HalfHeight-top)/cellHeight)), int(math.Floor((fish.Y+fish.HalfHeight-top)/cellHeight))
+		for y := max(y0, 0); y <= min(y1, height-1); y++ {
+			for x := max(x0, 0); x <= min(x1, width-1); x++ {
+				frame[(channel*height+y)*width+x] = 1
+			}
+		}
+	}
+	for i := range g.fish {
+		fish := &g.fish[i]
+		switch {
+		case fish.Dead:
+		case fish.Plane != player.Plane:
+			paint(fish, 2)
+		case fish.Size > player.Size:
+			paint(fish, 1)
+		default:
+			paint(fish, 0)
+		}
+	}
+	paint(&player.Fish, 3)
+	return frame
+}
+
+func (env *Environment) Reset(seed int64, options EnvOptions) (*Observation, error) {
+	if options.FrameSkip <= 0 {
+		options.FrameSkip = 1
+	}
+	if options.MaxTicks <= 0 {
+		options.MaxTicks = 10 * 60 * 60
+	}
+	if options.DeathPenalty == nil {
+		penalty := 100.0
+		options.DeathPenalty = &penalty
+	}
+	if options.ObservedFish <= 0 {
+		options.ObservedFish = defaultObservedFish
+	}
+	if options.FrameWidth <= 0 || options.FrameHeight <= 0 {
+		options.FrameWidth, options.FrameHeight = 96, 54
+	}
+	env.options = options
+	g := env.game
+	g.rng = rand.New(rand.NewSource(seed))
+	settings := map[int]float64{optionPlanes: options.Planes, optionFishAmount: options.FishAmount, optionFishSpeed: options.FishSpeed, optionFishSize: options.FishSize, optionPlayers: 1}
+	for _, option := range []int{optionPlanes, optionFishAmount, optionFishSpeed, optionFishSize, optionPlayers} {
+		if value := settings[option]; value != 0 {
+			if err := g.SetOption(option, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	g.ApplyOptions()
+	g.Start()
+	g.players[0].Input = env.input
+	env.input.Frame = InputFrame{}
+	env.lastScore, env.ticks, env.done = 0, 0, false
+	return env.Observe(), nil
+}
+
+func (env *Environment) Step(action EnvAction) (obs *Observation, reward float64, done bool) {
+	g := env.game
+	if env.done {
+		return env.Observe(), 0, true
+	}
+	env.input.Frame = InputFrame{DriveX: action.DriveX, DriveY: action.DriveY, Plane: action.Plane, Dash: action.Dash}
+	for i := 0; i < env.options.FrameSkip && g.gameState == gameRunning && !g.players[0].Dead; i++ {
+		g.Update()
+		env.ticks++
+	}
+	reward = g.score - env.lastScore
+	env.lastScore = g.score
+	switch {
+	case g.players[0].Dead:
+		reward -= *env.options.DeathPenalty
+		done = true
+	case g.gameState != gameRunning, env.ticks >= env.options.MaxTicks:
+		done = true
+	}
+	env.done = done
+	return env.Observe(), reward, done
+}
+
+// Serve answers one JSON request per line until the peer closes the stream or sends "close".
+func (env *Environment) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		var request EnvRequest
+		response := EnvResponse{}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = err.Error()
+		} else {
+			switch request.Method {
+			case "reset":
+				obs, err := env.Reset(request.Seed, request.Options)
+				if err != nil {
+					response.Error = err.Error()
+				}
+				response.Observation = obs
+			case "step":
+				response.Observation, response.Reward, response.Done = env.Step(request.Action)
+			case "close":
+				return nil
+			default:
+				response.Error = fmt.Sprintf("unknown method %q", request.Method)
+			}
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ServeEnvironment serves on stdio when addr is "stdio", otherwise listens on TCP with a separate game per connection.
+func ServeEnvironment(addr string) error {
+	if addr == "stdio" {
+		return NewEnvironment().Serve(os.Stdin, os.Stdout)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("environment listening on %s", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := NewEnvironment().Serve(conn, conn); err != nil {
+				log.Printf("environment %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
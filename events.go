@@ -0,0 +1,65 @@
+package main
+
+const (
+	eventStart = iota
+	eventTick
+	eventEat
+	eventDeath
+	eventPlaneSwitch
+	eventLunge
+	eventGameOver
+	eventVictory
+	eventPuff
+	eventLeave
+)
+
+// GameEvent is something that happened in a run. Player is the player fish involved, if any;
+// Fish is the prey for eventEat, the killer for eventDeath, the shark for eventLunge and the puffer for eventPuff.
+// eventLeave means a run was given up unfinished, for the menu, the lobby or by closing the window.
+type GameEvent struct {
+	Kind    int
+	Player  *PlayerFish
+	Fish    *Fish
+	Bonuses []ScoreBonus
+}
+
+// Notify passes an event to everything that keeps track of the game; none of them may change the simulation.
+func (g *Game) Notify(event GameEvent) {
+	if g.metrics != nil {
+		g.metrics.Handle(event)
+	}
+	if g.sound != nil {
+		g.sound.Handle(event)
+	}
+	g.achievements.Handle(g, event)
+	g.statistics.Handle(g, event)
+	g.HandleRecords(event)
+	g.RecordDeathCause(event)
+	g.EmitParticles(event)
+}
+
+// LeaveRun lets everything that keeps track of the game know when the players walk away from a run instead of finishing it.
+func (g *Game) LeaveRun() {
+	if g.gameState == gameRunning {
+		g.Notify(GameEvent{Kind: eventLeave})
+	}
+}
+
+// TracksProgress says whether play counts towards achievements and statistics:
+// not the autopilot, not a headless run and not somebody else's fish online. A nil player stands for the whole game.
+func (g *Game) TracksProgress(player *PlayerFish) bool {
+	if g.headless || g.demo || g.netAutopilot {
+		return false
+	}
+	return player == nil || g.net == nil || player.Index == g.net.local
+}
+
+// PlayerOf finds the player a fish belongs to, for events where a player is the prey.
+func (g *Game) PlayerOf(fish *Fish) *PlayerFish {
+	for i := range g.players {
+		if &g.players[i].Fish == fish {
+			return &g.players[i]
+		}
+	}
+	return nil
+}
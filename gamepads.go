@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const noticeFrames = 4 * 60
+
+// rawGamepadButtons is the usual XInput order, used for pads that have no standard layout mapping.
+var rawGamepadButtons = map[ebiten.StandardGamepadButton]ebiten.GamepadButton{
+	ebiten.StandardGamepadButtonRightBottom:   ebiten.GamepadButton0,
+	ebiten.StandardGamepadButtonRightRight:    ebiten.GamepadButton1,
+	ebiten.StandardGamepadButtonRightLeft:     ebiten.GamepadButton2,
+	ebiten.StandardGamepadButtonRightTop:      ebiten.GamepadButton3,
+	ebiten.StandardGamepadButtonFrontTopLeft:  ebiten.GamepadButton4,
+	ebiten.StandardGamepadButtonFrontTopRight: ebiten.GamepadButton5,
+	ebiten.StandardGamepadButtonCenterLeft:    ebiten.GamepadButton6,
+	ebiten.StandardGamepadButtonCenterRight:   ebiten.GamepadButton7,
+	ebiten.StandardGamepadButtonLeftStick:     ebiten.GamepadButton8,
+	ebiten.StandardGamepadButtonRightStick:    ebiten.GamepadButton9,
+	ebiten.StandardGamepadButtonLeftTop:       ebiten.GamepadButton10,
+	ebiten.StandardGamepadButtonLeftRight:     ebiten.GamepadButton11,
+	ebiten.StandardGamepadButtonLeftBottom:    ebiten.GamepadButton12,
+	ebiten.StandardGamepadButtonLeftLeft:      ebiten.GamepadButton13,
+}
+
+func (g *Game) ConnectGamepad(id ebiten.GamepadID) {
+	owner, first := "", !g.hasGamepad
+	if first {
+		g.gamepadId, g.hasGamepad = id, true
+	}
+	switch {
+	case len(g.players) > 1:
+		for i := range g.players {
+			device := &g.players[i].Device
+			if !device.HasGamepad && (device.Keys == nil || g.gameState != gameRunning) {
+				device.Gamepad, device.HasGamepad = id, true
+				owner = fmt.Sprintf("P%d", i+1)
+				break
+			}
+		}
+	case first && len(g.players) == 1:
+		g.players[0].Device.Gamepad, g.players[0].Device.HasGamepad = id, true
+		owner = "P1"
+	}
+	message := g.tr("Controller connected: %s", ebiten.GamepadName(id))
+	if owner != "" {
+		message += " (" + owner + ")"
+	}
+	g.ShowNotice(message)
+}
+
+func (g *Game) DisconnectGamepad(id ebiten.GamepadID) {
+	inUse := false
+	if g.hasGamepad && g.gamepadId == id {
+		inUse = true
+		g.hasGamepad = false
+		if len(g.gamepadIds) > 0 {
+			g.gamepadId, g.hasGamepad = g.gamepadIds[0], true
+		}
+	}
+	for i := range g.players {
+		device := &g.players[i].Device
+		if device.HasGamepad && device.Gamepad == id {
+			inUse = true
+			device.HasGamepad = false
+			if len(g.players) == 1 && g.hasGamepad {
+				device.Gamepad, device.HasGamepad = g.gamepadId, true
+			}
+		}
+	}
+	if inUse && g.gameState == gameRunning && !g.paused {
+		g.paused = true
+		g.ShowNotice(g.tr("Controller disconnected, game paused. Reconnect it or press P to go on."))
+		return
+	}
+	g.ShowNotice(g.tr("Controller disconnected."))
+}
+
+func (g *Game) DrawNotice() {
+	if g.noticeTimer <= 0 {
+		return
+	}
+	face := g.GetFontFace("small", false)
+	width, _ := text.Measure(g.notice, face, 0)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate((g.screenWidth-width)/2, 0.9*g.screenHeight)
+	op.ColorScale.ScaleWithColor(color.RGBA{255, 200, 0, 255})
+	op.ColorScale.ScaleAlpha(float32(min(1, g.noticeTimer/60)))
+	text.Draw(g.screen, g.notice, face, op)
+}
+
+func (g *Game) ShowNotice(message string) {
+	g.notice = message
+	g.noticeTimer = noticeFrames
+}
+
+// UpdateGamepads follows controllers being plugged in and out, and lets the single player switch to whichever pad was touched last.
+func (g *Game) UpdateGamepads() {
+	if g.headless {
+		return
+	}
+	if g.noticeTimer > 0 {
+		g.noticeTimer--
+	}
+	disconnected := slices.DeleteFunc(slices.Clone(g.gamepadIds), func(id ebiten.GamepadID) bool { return !inpututil.IsGamepadJustDisconnected(id) })
+	for _, id := range disconnected {
+		g.gamepadIds = slices.DeleteFunc(g.gamepadIds, func(other ebiten.GamepadID) bool { return other == id })
+		g.DisconnectGamepad(id)
+	}
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		if !slices.Contains(g.gamepadIds, id) {
+			g.gamepadIds = append(g.gamepadIds, id)
+		}
+		g.ConnectGamepad(id)
+	}
+	if len(g.players) == 1 {
+		for _, id := range g.gamepadIds {
+			if id != g.gamepadId && len(inpututil.AppendJustPressedGamepadButtons(id, nil)) > 0 {
+				g.gamepadId, g.hasGamepad = id, true
+				g.players[0].Device.Gamepad, g.players[0].Device.HasGamepad = id, true
+			}
+		}
+	}
+}
+
+func gamepadStick(id ebiten.GamepadID) (x, y float64) {
+	if ebiten.IsStandardGamepadLayoutAvailable(id) {
+		return ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal), ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+	}
+	if ebiten.GamepadAxisCount(id) < 2 {
+		return 0, 0
+	}
+	return ebiten.GamepadAxisValue(id, 0), ebiten.GamepadAxisValue(id, 1)
+}
+
+func isGamepadButtonsPressed(id ebiten.GamepadID, just bool, buttons ...ebiten.StandardGamepadButton) (pressed bool) {
+	if ebiten.IsStandardGamepadLayoutAvailable(id) {
+		method := ebiten.IsStandardGamepadButtonPressed
+		if just {
+			method = inpututil.IsStandardGamepadButtonJustPressed
+		}
+		for _, button := range buttons {
+			if method(id, button) {
+				pressed = true
+			}
+		}
+		return
+	}
+	method := ebiten.IsGamepadButtonPressed
+	if just {
+		method = inpututil.IsGamepadButtonJustPressed
+	}
+	for _, button := range buttons {
+		if raw, ok := rawGamepadButtons[button]; ok && int(raw) < ebiten.GamepadButtonCount(id) && method(id, raw) {
+			pressed = true
+		}
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// languageCodes are the shipped catalogues, in the order the Language option lists them.
+var languageCodes = []string{"en", "ru"}
+
+//go:embed resources/lang/*.json
+var languageFiles embed.FS
+
+// Language is a message catalogue. Messages are keyed by the English text, format verbs included;
+// Plurals hold one form per plural category of the language, keyed by the English "other" form.
+type Language struct {
+	Code     string              `json:"code"`
+	Name     string              `json:"name"`
+	Messages map[string]string   `json:"messages"`
+	Plurals  map[string][]string `json:"plurals"`
+}
+
+// languages are shared by every game in the process, headless ones in other goroutines included,
+// so they are loaded once and never changed; each game keeps its own choice.
+var (
+	languages     []Language
+	languagesOnce sync.Once
+)
+
+func loadLanguages() {
+	languagesOnce.Do(func() {
+		for _, code := range languageCodes {
+			data, err := languageFiles.ReadFile("resources/lang/" + code + ".json")
+			if err != nil {
+				log.Fatal(err)
+			}
+			var lang Language
+			if err := json.Unmarshal(data, &lang); err != nil {
+				log.Fatalf("%s.json: %v", code, err)
+			}
+			languages = append(languages, lang)
+		}
+	})
+}
+
+// systemLanguage guesses the player's language from the locale variables, falling back to English.
+func systemLanguage() float64 {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(variable)
+		if locale == "" {
+			continue
+		}
+		for i, code := range languageCodes {
+			if strings.HasPrefix(locale, code) {
+				return float64(i)
+			}
+		}
+		break
+	}
+	return 0
+}
+
+func languageIndex(code string) (float64, error) {
+	for i, known := range languageCodes {
+		if known == code {
+			return float64(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown language %q, expected one of %v", code, languageCodes)
+}
+
+func (g *Game) ApplyLanguage() {
+	g.language = &languages[int(g.optionsMenu[optionLanguage].GetValue())]
+}
+
+// tr translates a message and fills in its arguments. Messages missing from the catalogue stay in English.
+func (g *Game) tr(message string, args ...any) string {
+	if translated, ok := g.language.Messages[message]; ok {
+		message = translated
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// trn is tr for messages that depend on a count; n picks the plural form.
+func (g *Game) trn(message string, n int, args ...any) string {
+	if forms := g.language.Plurals[message]; len(forms) > 0 {
+		message = forms[min(pluralCategory(g.language.Code, n), len(forms)-1)]
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// pluralCategory follows the CLDR rules: English has one and other, Russian one, few and many.
+func pluralCategory(code string, n int) int {
+	if n < 0 {
+		n = -n
+	}
+	switch code {
+	case "ru":
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return 0
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return 1
+		}
+		return 2
+	}
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+// wrapText breaks a line at spaces so that it fits the width; translations are often longer than the English text.
+func wrapText(line string, face text.Face, width float64) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(line) {
+		candidate := strings.TrimSpace(current + " " + word)
+		if w, _ := text.Measure(candidate, face, 0); w > width && current != "" {
+			lines = append(lines, current)
+			candidate = word
+		}
+		current = candidate
+	}
+	return append(lines, current)
+}
+
+// DrawCentered draws a line centered horizontally at y; op may carry colors and is not modified.
+func (g *Game) DrawCentered(line string, face text.Face, y float64, op *text.DrawOptions) {
+	centered := &text.DrawOptions{}
+	if op != nil {
+		*centered = *op
+	}
+	width, _ := text.Measure(line, face, 0)
+	centered.GeoM.Translate((g.screenWidth-width)/2, y)
+	text.Draw(g.screen, line, face, centered)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// InputFrame is everything a player fish can do in one tick.
+type InputFrame struct {
+	DriveX, DriveY float64
+	Plane          bool
+	Dash           bool
+}
+
+type InputSource interface {
+	Poll(fish *PlayerFish) InputFrame
+}
+
+// Policy is an AI agent deciding what the fish does next, given the fish and the rest of the ocean.
+type Policy interface {
+	Decide(fish *PlayerFish, others []Fish) InputFrame
+}
+
+type DeviceInput struct{}
+
+type AgentInput struct {
+	Policy Policy
+}
+
+// Replay is what it takes to play a run again tick for tick: the seed and options it started with, and every player's inputs.
+type Replay struct {
+	Seed    int64           `json:"seed"`
+	Options map[int]float64 `json:"options"`
+	Frames  [][]InputFrame  `json:"frames"`
+}
+
+// replayedOptions are the options that shape the simulation, the same ones the host dictates online plus the player count.
+var replayedOptions = append([]int{optionPlayers}, netSyncedOptions...)
+
+type RecordingInput struct {
+	Source InputSource
+	Replay *Replay
+	Player int
+}
+
+type ReplayInput struct {
+	Replay   *Replay
+	Player   int
+	Position int
+}
+
+type ScriptedInput struct {
+	Script func(tick int, fish *PlayerFish) InputFrame
+	Tick   int
+}
+
+func (input *AgentInput) Poll(fish *PlayerFish) InputFrame {
+	return input.Policy.Decide(fish, fish.game.fish)
+}
+
+func (input *DeviceInput) Poll(fish *PlayerFish) (frame InputFrame) {
+	if fish.IsActionPressed(actionUp, false) {
+		frame.DriveY -= 1
+	}
+	if fish.IsActionPressed(actionDown, false) {
+		frame.DriveY += 1
+	}
+	if fish.IsActionPressed(actionLeft, false) {
+		frame.DriveX -= 1
+	}
+	if fish.IsActionPressed(actionRight, false) {
+		frame.DriveX += 1
+	}
+	frame.Plane = fish.IsActionPressed(actionPlane, true)
+	frame.Dash = fish.IsActionPressed(actionDash, true)
+	if fish.Device.Mouse && ebiten.IsMouseButtonPressed(ebiten.MouseButton0) {
+		jx, jy := ebiten.CursorPosition()
+		wx, wy := fish.game.ToWorld(float64(jx), float64(jy))
+		mx, my := wx-fish.X, wy-fish.Y
+		if math.Hypot(mx, my) >= fish.HalfHeight {
+			frame.DriveX, frame.DriveY = mx, my
+		}
+	}
+	if id, ok := fish.GamepadID(); ok {
+		gx, gy := gamepadStick(id)
+		if math.Hypot(gx, gy) > 0.25 {
+			frame.DriveX, frame.DriveY = gx, gy
+		}
+	}
+	return
+}
+
+func (input *RecordingInput) Poll(fish *PlayerFish) InputFrame {
+	frame := input.Source.Poll(fish)
+	input.Replay.Frames[input.Player] = append(input.Replay.Frames[input.Player], frame)
+	return frame
+}
+
+// Poll keeps the fish idle once the recording runs out.
+func (input *ReplayInput) Poll(fish *PlayerFish) InputFrame {
+	frames := input.Replay.Frames[input.Player]
+	if input.Position >= len(frames) {
+		return InputFrame{}
+	}
+	frame := frames[input.Position]
+	input.Position++
+	return frame
+}
+
+func (input *ScriptedInput) Poll(fish *PlayerFish) InputFrame {
+	frame := input.Script(input.Tick, fish)
+	input.Tick++
+	return frame
+}
+
+// StartRecording starts a run with the current options and records it; player i plays sources[i], or its usual devices when there are fewer sources.
+func (g *Game) StartRecording(seed int64, sources ...InputSource) *Replay {
+	replay := &Replay{Seed: seed, Options: map[int]float64{}}
+	for _, option := range replayedOptions {
+		replay.Options[option] = g.optionsMenu[option].GetValue()
+	}
+	g.rng = rand.New(rand.NewSource(seed))
+	g.ApplyOptions()
+	g.Start()
+	replay.Frames = make([][]InputFrame, len(g.players))
+	for i := range g.players {
+		source := g.players[i].Input
+		if i < len(sources) {
+			source = sources[i]
+		}
+		g.players[i].Input = &RecordingInput{Source: source, Replay: replay, Player: i}
+	}
+	return replay
+}
+
+// StartReplay restores the options and seed of a recorded run and starts it again with the recorded inputs.
+func (g *Game) StartReplay(replay *Replay) error {
+	for option, value := range replay.Options {
+		if err := g.SetOption(option, value); err != nil {
+			return err
+		}
+	}
+	g.rng = rand.New(rand.NewSource(replay.Seed))
+	g.ApplyOptions()
+	g.Start()
+	if len(replay.Frames) != len(g.players) {
+		return fmt.Errorf("the replay has inputs for %d players, the game has %d", len(replay.Frames), len(g.players))
+	}
+	for i := range g.players {
+		g.players[i].Input = &ReplayInput{Replay: replay, Player: i}
+	}
+	return nil
+}
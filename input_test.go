@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// zigzag swims back and forth, switching planes and dashing now and then, so a replay exercises every kind of input.
+func zigzag(tick int, fish *PlayerFish) InputFrame {
+	frame := InputFrame{DriveX: 1, DriveY: 0.5, Plane: tick%90 == 45, Dash: tick%120 == 60}
+	if tick/150%2 == 1 {
+		frame.DriveX, frame.DriveY = -1, -0.5
+	}
+	return frame
+}
+
+func runTicks(t *testing.T, g *Game, ticks int) int {
+	t.Helper()
+	tick := 0
+	for ; tick < ticks && g.gameState == gameRunning; tick++ {
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tick
+}
+
+func TestScriptedInput(t *testing.T) {
+	g := NewHeadlessGame()
+	g.rng.Seed(1)
+	g.ApplyOptions()
+	g.Start()
+	input := &ScriptedInput{Script: func(tick int, fish *PlayerFish) InputFrame { return InputFrame{DriveX: 1} }}
+	player := &g.players[0]
+	player.Input = input
+	startX := player.X
+	ticks := runTicks(t, g, 60)
+	if input.Tick != ticks {
+		t.Errorf("the script ran %d ticks, want %d", input.Tick, ticks)
+	}
+	if player.X <= startX {
+		t.Errorf("the player drove right from %v to %v", startX, player.X)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	recorded := NewHeadlessGame()
+	for option, value := range map[int]float64{optionPlanes: 2, optionFishAmount: 25, optionFishSpeed: 2, optionOceanSize: 2} {
+		if err := recorded.SetOption(option, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replay := recorded.StartRecording(42, &ScriptedInput{Script: zigzag})
+	ticks := runTicks(t, recorded, 20*60)
+	// A dead fish reads no input, so the recording stops short of the run when the player dies.
+	if frames := len(replay.Frames[0]); frames > ticks || frames < ticks && !recorded.players[0].Dead {
+		t.Fatalf("recorded %d frames over %d ticks", frames, ticks)
+	}
+
+	// The replay goes through JSON and into a game left on different options, as a saved replay would.
+	data, err := json.Marshal(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Replay
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	replayed := NewHeadlessGame()
+	if err := replayed.SetOption(optionFishAmount, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayed.StartReplay(&loaded); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.fishPerPlane != 25 || replayed.fishSpeedModifier != 2 {
+		t.Errorf("the replay runs with %v fish per plane at speed %v, want 25 at 2", replayed.fishPerPlane, replayed.fishSpeedModifier)
+	}
+	if got := runTicks(t, replayed, ticks); got != ticks {
+		t.Errorf("the replay stopped after %d ticks, the run lasted %d", got, ticks)
+	}
+	if replayed.StateHash() != recorded.StateHash() {
+		t.Errorf("the replay ended with the player at %v, %v, the run at %v, %v", replayed.players[0].X, replayed.players[0].Y, recorded.players[0].X, recorded.players[0].Y)
+	}
+}
+
+func TestReplayPlayerMismatch(t *testing.T) {
+	g := NewHeadlessGame()
+	replay := &Replay{Seed: 1, Options: map[int]float64{optionPlayers: 2}, Frames: make([][]InputFrame, 1)}
+	if err := g.StartReplay(replay); err == nil {
+		t.Error("a replay with inputs for 1 of 2 players started")
+	}
+}
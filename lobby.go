@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	lobbyItemPreset = iota
+	lobbyItemAddress
+	lobbyItemHost
+	lobbyItemJoin
+	lobbyItemStart
+	lobbyItemBack
+)
+
+const lobbyAddressChars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.:-[]"
+
+// netPresets line up with the Preset titles; the first one keeps the host's own options.
+var netPresets = []map[int]float64{
+	nil,
+	{optionPlanes: 2, optionFishAmount: 15, optionFishSpeed: 1, optionFishSize: 45, optionFishReactions: 1, optionPlayerCollisions: playerCollisionsIgnore},
+	{optionPlanes: 2, optionFishAmount: 25, optionFishSpeed: 2, optionFishSize: 75, optionFishReactions: 1, optionPlayerCollisions: playerCollisionsEat},
+	{optionPlanes: 1, optionFishAmount: 20, optionFishSpeed: 1, optionFishSize: 60, optionFishReactions: 1, optionPlayerCollisions: playerCollisionsBump},
+}
+
+func (g *Game) CreateLobbyMenu() {
+	items := []MenuItem{
+		{title: "Preset", titles: []string{"my options", "classic", "frenzy", "arena"}, values: []float64{0, 1, 2, 3}},
+		{title: "Address"},
+		{title: "Host game"},
+		{title: "Join game"},
+		{title: "Start"},
+		{title: "Back"},
+	}
+	g.lobbyMenu = append(g.lobbyMenu, items...)
+	g.lobbyAddress = netDefaultAddress
+}
+
+func (g *Game) DrawLobby() {
+	g.DrawAllNpcFish()
+	address := g.lobbyAddress
+	if g.lobbyEditing {
+		address += "_"
+	}
+	g.lobbyMenu[lobbyItemAddress].titles = []string{address}
+	for i, item := range g.lobbyMenu {
+		item.Draw(g, i == g.activeMenuIndex)
+	}
+	hint := g.tr("Host a game or type the host's address and join it. Two copies on one machine can use 127.0.0.1")
+	if g.net != nil {
+		hint = g.net.Status(g)
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.1*g.screenWidth, 0.8*g.screenHeight)
+	op.ColorScale.ScaleWithColor(color.RGBA{255, 200, 0, 255})
+	text.Draw(g.screen, hint, g.GetFontFace("small", false), op)
+}
+
+// DrawNetStatus keeps the connection state on screen for the whole online session.
+func (g *Game) DrawNetStatus() {
+	s := g.net
+	if s == nil || g.gameState == gameLobby {
+		return
+	}
+	status := g.tr("ONLINE P%d  DELAY %d  PING %dms", s.local+1, s.delay, s.rtt.Milliseconds())
+	switch {
+	case g.gameState == gameRunning && s.stalledTicks > netStallNotice:
+		status += "  " + g.tr("WAITING FOR OTHER PLAYERS...")
+	case g.gameState != gameRunning && s.host:
+		status += "  " + g.tr("CONFIRM: NEXT ROUND  BACK: LEAVE")
+	case g.gameState != gameRunning:
+		status += "  " + g.tr("WAITING FOR THE HOST  BACK: LEAVE")
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.02*g.screenWidth, 0.95*g.screenHeight)
+	text.Draw(g.screen, status, g.GetFontFace("small", false), op)
+}
+
+func (g *Game) GoToLobby() {
+	g.LeaveRun()
+	g.gameState = gameLobby
+	g.activeMenuIndex = lobbyItemHost
+	g.lobbyEditing = false
+}
+
+func (g *Game) LobbyCycle() error {
+	for i := range g.fish {
+		g.fish[i].Move()
+	}
+	if g.lobbyEditing {
+		g.EditLobbyAddress()
+		return nil
+	}
+	if g.IsActionPressed(actionBack, true) {
+		g.LeaveLobby()
+		return nil
+	}
+	if g.MenuButtonDown() {
+		g.activeMenuIndex = int(math.Min(float64(g.activeMenuIndex+1), float64(lobbyItemBack)))
+	}
+	if g.MenuButtonUp() {
+		g.activeMenuIndex = int(math.Max(float64(g.activeMenuIndex-1), 0))
+	}
+	if g.HasMouseMoved() {
+		for i := range g.lobbyMenu {
+			if g.lobbyMenu[i].DetectHover() {
+				g.activeMenuIndex = i
+			}
+		}
+	}
+	preset := &g.lobbyMenu[lobbyItemPreset]
+	if g.activeMenuIndex == lobbyItemPreset {
+		if g.MenuButtonRight() {
+			preset.ShiftRight()
+		}
+		if g.MenuButtonLeft() {
+			preset.ShiftLeft()
+		}
+	}
+	if !g.IsActionPressed(actionConfirm, true) {
+		return nil
+	}
+	var err error
+	switch g.activeMenuIndex {
+	case lobbyItemPreset:
+		preset.CycleRight()
+	case lobbyItemAddress:
+		g.lobbyEditing = g.net == nil
+	case lobbyItemHost:
+		if g.net == nil {
+			err = g.HostSession(g.lobbyAddress)
+		}
+	case lobbyItemJoin:
+		if g.net == nil {
+			err = g.JoinSession(g.lobbyAddress)
+		}
+	case lobbyItemStart:
+		if g.net != nil && g.net.host {
+			g.net.preset = netPresets[int(preset.GetValue())]
+			g.StartRound()
+		}
+	case lobbyItemBack:
+		g.LeaveLobby()
+	}
+	if err != nil {
+		g.ShowNotice(err.Error())
+	}
+	return nil
+}
+
+// EditLobbyAddress takes typed characters until Enter or Escape.
+func (g *Game) EditLobbyAddress() {
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if strings.ContainsRune(lobbyAddressChars, r) {
+			g.lobbyAddress += string(r)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.lobbyAddress) > 0 {
+		g.lobbyAddress = g.lobbyAddress[:len(g.lobbyAddress)-1]
+	}
+	if isAnyOfKeysPressed(true, ebiten.KeyEnter, ebiten.KeyNumpadEnter, ebiten.KeyEscape) {
+		g.lobbyEditing = false
+	}
+}
+
+func (g *Game) LeaveLobby() {
+	if g.net != nil {
+		g.LeaveSession("")
+		return
+	}
+	g.GoToMenu(false)
+}
+
+func (s *NetSession) Status(g *Game) string {
+	if !s.host {
+		return s.status
+	}
+	pings := []string{}
+	for _, peer := range s.peers {
+		if peer.joined {
+			pings = append(pings, fmt.Sprintf("P%d %dms", peer.player+1, peer.rtt.Milliseconds()))
+		}
+	}
+	status := g.tr("%s. Players: %d/%d", s.status, s.playerCount, maxPlayers)
+	if len(pings) > 0 {
+		status += " (" + strings.Join(pings, ", ") + ")"
+	}
+	return status + ". " + g.tr("Start when everyone has joined")
+}
@@ -3,12 +3,14 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"math"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2/colorm"
@@ -42,96 +44,100 @@ var (
 	fixedsys []byte
 	//go:embed resources/AquaWow.otf
 	aquawow []byte
-	quotes  = []string{
-		"YOU DIED",
-		"You tried.",
-		"You're fry-ed.",
-		"Try again.",
-		"You've got a valuable lesson.",
-		"Press Spacebar to restart.",
-		"Be careful next time.",
-		"So it goes.",
-		"It happens.",
-		"Too greedy.",
-		"You just went belly up.",
-		"Well, at least give them indigestion.",
-		"You still have two ways out.",
-		"Some fish can swallow prey 3 times their own size. Alas, you're not one of those.",
-		"There's always a bigger fish.",
-		"Don't bite more than you can swallow.",
-		"That's it, no more Bill, his greed got him killed.",
-		"Oops, someone got greedy.",
-		"Eat the fish smaller than you, or be eaten by a bigger fish.",
-		"Use the keyboard or the mouse to move, whatever suits you best.",
-		"If you're cornered, press Spacebar or RMB to dodge to back or front plane.",
-		"Press H or click the little arrow in the main menu to hide it and just relax.",
-		"Press P or Enter to pause the game.",
-		"Sharks attack anything they see as a good meal, for better or worse.",
-		"Goldfish will try to escape with a dodge and a dash.",
-		"Pufferfish are easily scared and puff up to make themselves inedible... or more delicious.",
-		"The bass will run away when threatened, but will always try to sneak back.",
-		"The jellyfish is brainless but not harmless. Still, just as edible as the other fish.",
-		"Remember, objects further to the back are bigger than they appear.",
-		"If you want more - or less - challenge, go to Options and play around.",
-		"The bigger the fish, the better the score, but don't get too greedy.",
-		"If the fish isn't afraid of you, that may be for a reason.",
-		"GULP",
-	}
+	//go:embed resources/Anonymous_Pro.ttf
+	anonymousPro []byte
 )
 
 const (
-	title           = "FISH 3.0D"
-	screenWidth     = 1920
-	screenHeight    = 1080
-	fishCount       = 10
-	maxPlanes       = 2
-	gameRunning     = 0
-	gameOver        = 2
-	gameVictory     = 3
-	gameMenu        = 4
-	gameOptionsMenu = 5
+	title            = "FISH 3.0D"
+	screenWidth      = 1920
+	screenHeight     = 1080
+	fishCount        = 10
+	maxPlanes        = 2
+	maxFishPerPlane  = 25
+	maxSpawnedFish   = 50
+	gameRunning      = 0
+	gameOver         = 2
+	gameVictory      = 3
+	gameMenu         = 4
+	gameOptionsMenu  = 5
+	gameControlsMenu = 6
+	gameLobby        = 7
+	gameAchievements = 8
+	gameStatistics   = 9
+
+	planeShiftFrames = 15
 )
 
-func getFishType(index, fishesCount float64) string {
-	switch {
-	case index == 0:
-		return "jelly"
-	case index < 0.35*fishesCount:
-		return "bass"
-	case index < 0.65*fishesCount:
-		return "goldfish"
-	case index < 0.85*fishesCount:
-		return "puffer"
-	default:
-		return "shark"
-	}
+const (
+	menuPlay = iota
+	menuOnline
+	menuAchievements
+	menuStatistics
+	menuOptions
+	menuQuit
+)
 
-}
+const (
+	optionPlanes = iota
+	optionFishAmount
+	optionFishSpeed
+	optionFishSize
+	optionFishReactions
+	optionOceanSize
+	optionFullscreen
+	optionDisplay
+	optionPlayers
+	optionPlayerCollisions
+	optionLanguage
+	optionVolume
+	optionMute
+	optionControls
+)
 
 type Fish struct {
-	Size                float64
-	HalfWidth           float64
-	HalfHeight          float64
-	Scale               float64
-	X                   float64
-	Y                   float64
-	SpeedX              float64
-	SpeedY              float64
-	FacingLeft          bool
-	Plane               float64
-	Dead                bool
-	Type                string
-	FrictionCoefficient float64
-	GraphUpdated        bool
-	Cooldown            float64
-	Image               *ebiten.Image
-	DrawOptions         *colorm.DrawImageOptions
-	Colorm              *colorm.ColorM
-	game                *Game
+	Size                    float64
+	HalfWidth               float64
+	HalfHeight              float64
+	Scale                   float64
+	X                       float64
+	Y                       float64
+	SpeedX                  float64
+	SpeedY                  float64
+	FacingLeft              bool
+	Plane                   float64
+	PlaneShift              float64
+	PrevPlane               float64
+	Dead                    bool
+	Type                    string
+	Sprite                  string
+	AccelerationCoefficient float64
+	FrictionCoefficient     float64
+	GraphUpdated            bool
+	Cooldown                float64
+	Glow                    float64
+	Tint                    color.Color
+	Image                   *ebiten.Image
+	Mask                    image.Image
+	DrawOptions             *colorm.DrawImageOptions
+	Colorm                  *colorm.ColorM
+	game                    *Game
 }
 
 type PlayerFish struct {
 	Fish
+	Combo        float64
+	ComboTimer   float64
+	DashCooldown float64
+	Device       InputDevice
+	DriveX       float64
+	DriveY       float64
+	Eaten        float64
+	Index        int
+	Input        InputSource
+	Score        float64
+	Stage        int
+	TurnRate     float64
 }
 
 func (fish *Fish) CooldownTick() {
@@ -147,6 +153,7 @@ func (fish *Fish) CooldownTick() {
 			fish.Cooldown = 60 * 20
 			fish.SpeedX /= speedFactor
 			fish.SpeedY /= speedFactor
+			fish.game.Notify(GameEvent{Kind: eventPuff, Fish: fish})
 		case fish.Cooldown > 60*18.5:
 			fish.SetSize(fish.Size * 1.01)
 		case fish.Cooldown > 60*15 && fish.Cooldown <= 60*16.5:
@@ -226,45 +233,104 @@ func (fish *Fish) Die() bool {
 func (fish *Fish) Draw() {
 	if fish.GraphUpdated {
 		fish.GraphReset()
-	} else {
-		fish.DrawOptions.GeoM.Translate(fish.SpeedX, fish.SpeedY)
 	}
-	colorm.DrawImage(fish.game.screen, fish.Image, *fish.Colorm, fish.DrawOptions)
+	op := *fish.DrawOptions
+	op.GeoM = fish.SpriteGeoM()
+	op.GeoM.Concat(fish.game.CameraGeoM())
+	if fish.PlaneShift > 0 && !fish.Dead {
+		fish.DrawShifting(&op)
+		return
+	}
+	colorm.DrawImage(fish.game.screen, fish.Image, *fish.Colorm, &op)
+}
+
+// DrawShifting cross-fades the front and back plane blend modes while the fish is moving between planes.
+func (fish *Fish) DrawShifting(shifting *colorm.DrawImageOptions) {
+	depth := math.Min(fish.VisualPlane(), 1)
+	layers := []struct {
+		blend ebiten.Blend
+		alpha float64
+	}{
+		{ebiten.BlendSourceOver, 1 - depth},
+		{ebiten.BlendXor, depth},
+	}
+	for _, layer := range layers {
+		if layer.alpha <= 0 {
+			continue
+		}
+		cm := *fish.Colorm
+		cm.Scale(1, 1, 1, layer.alpha)
+		op := *shifting
+		op.Blend = layer.blend
+		colorm.DrawImage(fish.game.screen, fish.Image, cm, &op)
+	}
 }
 
 func (fish *Fish) GraphReset() {
 	fish.DrawOptions.Blend = ebiten.BlendSourceOver
-	fish.DrawOptions.GeoM.Reset()
 	fish.Colorm.Reset()
-	flipX, flipY := float64(1), float64(1)
-	if fish.FacingLeft {
-		flipX = float64(-1)
-	}
 	if fish.Dead {
-		flipY = float64(-1)
 		fish.Colorm.ChangeHSV(0, 0, 2)
 		fish.Colorm.Scale(1, 1, 1, 0.25)
 	} else {
 		if fish.Plane > 0 {
 			fish.DrawOptions.Blend = ebiten.BlendXor
 		}
+		if fish.PlaneShift > 0 {
+			glow := math.Sin(math.Pi * fish.PlaneShift / planeShiftFrames)
+			fish.Colorm.Scale(1-0.4*glow, 1-0.2*glow, 1, 1)
+		}
+	}
+	if fish.Tint != nil {
+		fish.Colorm.ScaleWithColor(fish.Tint)
+	}
+	if fish.Glow > 0 {
+		glow := math.Sin(math.Pi * fish.Glow / evolutionFrames)
+		fish.Colorm.ChangeHSV(0, 1, 1+glow)
 	}
 	fish.DrawOptions.Filter = ebiten.FilterLinear
-	fish.DrawOptions.GeoM.Scale(fish.Scale*flipX, fish.Scale*flipY)
-	fish.DrawOptions.GeoM.Translate(fish.X-flipX*fish.HalfWidth, fish.Y-flipY*fish.HalfHeight)
+}
+
+// SpriteGeoM takes sprite pixels to the world. It is worked out from the simulated state alone, so collisions
+// come out the same whether the game is drawn or not, and however many ticks run between two frames.
+func (fish *Fish) SpriteGeoM() ebiten.GeoM {
+	flipX, flipY, pop := float64(1), float64(1), float64(1)
+	if fish.FacingLeft {
+		flipX = float64(-1)
+	}
+	if fish.Dead {
+		flipY = float64(-1)
+	}
+	if fish.Glow > 0 {
+		pop += 0.2 * math.Sin(math.Pi*fish.Glow/evolutionFrames)
+	}
+	var geoM ebiten.GeoM
+	geoM.Scale(pop*fish.Scale*flipX, pop*fish.Scale*flipY)
+	geoM.Translate(fish.X-pop*flipX*fish.HalfWidth, fish.Y-pop*flipY*fish.HalfHeight)
+	return geoM
 }
 
 func (fish *PlayerFish) Hit(target *Fish) {
 	if !target.Dead && !fish.Dead && fish.Overlap(target) {
 		if fish.Size < target.Size {
+			if fish.HasAbility("thick scales") && target.Size < fish.Size*1.15 {
+				return
+			}
 			fish.Die()
-			fish.game.VibrateGamepadHeavy()
+			fish.game.Notify(GameEvent{Kind: eventDeath, Player: fish, Fish: target})
+			fish.game.VibrateGamepadHeavy(fish.Device)
 		} else {
+			bonuses := fish.game.GetPreyBonuses(fish, target)
 			if target.Die() {
-				fish.SetSize(fish.Size + 1)
-				fish.game.UpdateScore(target.Size)
-				fish.game.VibrateGamepadQuick()
-				if fish.HalfWidth*2 > fish.game.screenWidth {
+				if target.Type == "player" {
+					fish.game.Notify(GameEvent{Kind: eventDeath, Player: fish.game.PlayerOf(target), Fish: &fish.Fish})
+				}
+				fish.Grow(1)
+				fish.game.UpdateScore(fish, target, bonuses)
+				fish.game.Notify(GameEvent{Kind: eventEat, Player: fish, Fish: target, Bonuses: bonuses})
+				fish.game.VibrateGamepadQuick(fish.Device)
+				// The goal is the reference screen width, so neither the window's shape nor a resize moves it.
+				if fish.HalfWidth*2 > screenWidth {
 					fish.game.Win()
 				}
 			}
@@ -282,16 +348,21 @@ func (fish *PlayerFish) Hunt(targets []Fish) {
 	}
 }
 
-func (fish *Fish) Init(g *Game, fishtype string) {
+func (fish *Fish) Init(g *Game, species Species) {
 	fish.game = g
-	fish.Type = fishtype
+	fish.Type = species.Behaviour
+	fish.Sprite = species.Sprite
 	fish.InitImage()
 
 }
 
-func (fish *PlayerFish) Init(g *Game) {
+func (fish *PlayerFish) Init(g *Game, index int) {
 	fish.game = g
+	fish.Index = index
+	fish.Tint = playerTints[index]
+	fish.Input = &DeviceInput{}
 	fish.Type = "player"
+	fish.Sprite = "player"
 	fish.InitImage()
 }
 
@@ -299,7 +370,10 @@ func (fish *Fish) InitImage() {
 	if fish.Image != nil {
 		fish.Image.Dispose()
 	}
-	fish.Image = ebiten.NewImageFromImage(fish.game.preloadedImages[fish.Type])
+	fish.Mask = fish.game.preloadedImages[fish.Sprite]
+	if !fish.game.headless {
+		fish.Image = ebiten.NewImageFromImage(fish.Mask)
+	}
 	fish.DrawOptions = new(colorm.DrawImageOptions)
 	fish.Colorm = new(colorm.ColorM)
 }
@@ -308,15 +382,16 @@ func (fish *Fish) IsOutOfBounds() (isOut, vertical bool) {
 	if fish.Cooldown != 0 {
 		return false, false
 	}
-	horizontal := !(fish.X >= -fish.HalfWidth && fish.X <= fish.game.screenWidth+fish.HalfWidth)
-	vertical = !(fish.Y >= -fish.HalfHeight && fish.Y <= fish.game.screenHeight+fish.HalfHeight)
+	left, top, right, bottom := fish.game.SpawnArea()
+	horizontal := !(fish.X >= left-fish.HalfWidth && fish.X <= right+fish.HalfWidth)
+	vertical = !(fish.Y >= top-fish.HalfHeight && fish.Y <= bottom+fish.HalfHeight)
 	isOut = horizontal || vertical
 	return
 }
 
 func (fish *PlayerFish) IsOutOfBounds() (isOut, vertical bool) {
-	horizontal := (fish.SpeedX < 0 && fish.X < fish.HalfWidth) || (fish.SpeedX > 0 && fish.X > fish.game.screenWidth-fish.HalfWidth)
-	vertical = (fish.SpeedY < 0 && fish.Y < fish.HalfHeight) || (fish.SpeedY > 0 && fish.Y > fish.game.screenHeight+fish.HalfHeight)
+	horizontal := (fish.SpeedX < 0 && fish.X < fish.HalfWidth) || (fish.SpeedX > 0 && fish.X > fish.game.worldWidth-fish.HalfWidth)
+	vertical = (fish.SpeedY < 0 && fish.Y < fish.HalfHeight) || (fish.SpeedY > 0 && fish.Y > fish.game.worldHeight+fish.HalfHeight)
 	isOut = horizontal || vertical
 	return
 }
@@ -327,35 +402,41 @@ func (fish *Fish) Move() {
 		fish.Randomize()
 	}
 	fish.CooldownTick()
+	fish.PlaneShiftTick()
+	fish.game.EmitTrail(fish)
 
 }
 
 func (fish *PlayerFish) Move() {
-	driveX, driveY := fish.ReadInput()
+	driveX, driveY := fish.Steer(fish.ReadInput())
 	fish.Swim(driveX, driveY)
 	if out, vertical := fish.IsOutOfBounds(); out {
 		fish.Rebound(vertical)
 	}
+	fish.PlaneShiftTick()
+	fish.game.EmitTrail(&fish.Fish)
+	fish.StageTick()
+	fish.ComboTick()
 	fish.Hunt(fish.game.fish)
 }
 
 func (fish *Fish) Overlap(target *Fish) bool {
-	if fish.Plane != target.Plane {
+	if fish.Plane != target.Plane || fish.PlaneShift > 0 || target.PlaneShift > 0 {
 		return false
 	}
 	fRectangle := image.Rect(int(fish.X-fish.HalfWidth), int(fish.Y-fish.HalfHeight), int(fish.X+fish.HalfWidth), int(fish.Y+fish.HalfHeight))
 	tRectangle := image.Rect(int(target.X-target.HalfWidth), int(target.Y-target.HalfHeight), int(target.X+target.HalfWidth), int(target.Y+target.HalfHeight))
 	intersection := fRectangle.Intersect(tRectangle)
 	if !intersection.Empty() {
-		matrix, tmatrix := fish.DrawOptions.GeoM, target.DrawOptions.GeoM
+		matrix, tmatrix := fish.SpriteGeoM(), target.SpriteGeoM()
 		matrix.Invert()
 		tmatrix.Invert()
 		for y := intersection.Min.Y; y < intersection.Max.Y; y++ {
 			for x := intersection.Min.X; x < intersection.Max.X; x++ {
 				x0, y0 := matrix.Apply(float64(x), float64(y))
 				tx0, ty0 := tmatrix.Apply(float64(x), float64(y))
-				_, _, _, a := fish.Image.At(int(x0), int(y0)).RGBA()
-				_, _, _, ta := target.Image.At(int(tx0), int(ty0)).RGBA()
+				_, _, _, a := fish.Mask.At(int(x0), int(y0)).RGBA()
+				_, _, _, ta := target.Mask.At(int(tx0), int(ty0)).RGBA()
 				if a != 0 && ta != 0 {
 					return true
 				}
@@ -365,6 +446,14 @@ func (fish *Fish) Overlap(target *Fish) bool {
 	return false
 }
 
+func (fish *Fish) PlaneShiftTick() {
+	if fish.PlaneShift == 0 {
+		return
+	}
+	fish.PlaneShift--
+	fish.ResizeSprite()
+}
+
 func (fish *Fish) ProximityAlert(attacker *PlayerFish) {
 	distance := math.Sqrt(math.Pow(fish.X-attacker.X, 2) + math.Pow(fish.Y-attacker.Y, 2))
 	if fish.Dead || fish.Cooldown != 0 || fish.Plane != attacker.Plane || distance > 4*(fish.HalfWidth+attacker.HalfWidth) {
@@ -387,6 +476,7 @@ func (fish *Fish) ProximityAlert(attacker *PlayerFish) {
 	if fish.Type == "shark" && attacker.Size > fish.Size/2 && attacker.Size < fish.Size*1.5 {
 		fish.Cooldown = -1
 		fish.SpeedX, fish.SpeedY = (attacker.X-fish.X)/90, (attacker.Y-fish.Y)/90
+		fish.game.Notify(GameEvent{Kind: eventLunge, Player: attacker, Fish: fish})
 		fish.FacingLeft = fish.SpeedX < 0
 		fish.GraphUpdated = true
 	}
@@ -395,29 +485,31 @@ func (fish *Fish) ProximityAlert(attacker *PlayerFish) {
 func (fish *Fish) Randomize() {
 	fish.Dead = false
 	fish.Cooldown = 0
-	fish.Plane = float64(rand.Intn(int(fish.game.planeCount)))
-	fish.SetSize(float64(rand.Intn(int(fish.game.fishSizeCap)-5) + 5))
-	fish.SpeedX = float64(rand.Intn(3) + 1)
-	fish.SpeedY = float64(rand.Intn(3) - 1)
-	reverse := rand.Intn(2)
+	fish.PlaneShift = 0
+	fish.Plane = float64(fish.game.rng.Intn(int(fish.game.planeCount)))
+	fish.SetSize(float64(fish.game.rng.Intn(int(fish.game.fishSizeCap)-5) + 5))
+	fish.SpeedX = float64(fish.game.rng.Intn(3) + 1)
+	fish.SpeedY = float64(fish.game.rng.Intn(3) - 1)
+	reverse := fish.game.rng.Intn(2)
 	if reverse == 1 {
 		fish.SpeedX *= -1
 	}
+	left, top, right, bottom := fish.game.SpawnArea()
 	if fish.Type == "jelly" {
 		fish.SpeedY = fish.SpeedX
 		fish.SpeedX = 0
-		fish.X = float64(rand.Intn(int(fish.game.screenWidth)))
+		fish.X = left + float64(fish.game.rng.Intn(int(right-left)))
 		if fish.SpeedY < 0 {
-			fish.Y = fish.game.screenHeight + fish.HalfHeight - 1
+			fish.Y = bottom + fish.HalfHeight - 1
 		} else {
-			fish.Y = 1 - fish.HalfHeight
+			fish.Y = top + 1 - fish.HalfHeight
 		}
 	} else {
-		fish.Y = float64(rand.Intn(int(fish.game.screenHeight)))
+		fish.Y = top + float64(fish.game.rng.Intn(int(bottom-top)))
 		if fish.SpeedX < 0 {
-			fish.X = fish.game.screenWidth + fish.HalfWidth - 1
+			fish.X = right + fish.HalfWidth - 1
 		} else {
-			fish.X = 1 - fish.HalfWidth
+			fish.X = left + 1 - fish.HalfWidth
 		}
 	}
 	fish.SpeedX, fish.SpeedY = fish.SpeedX*fish.game.fishSpeedModifier, fish.SpeedY*fish.game.fishSpeedModifier
@@ -430,49 +522,25 @@ func (fish *PlayerFish) ReadInput() (driveX, driveY float64) {
 	if fish.Dead {
 		return
 	}
-	driveX, driveY = 0, 0
-
-	if isAnyOfKeysPressed(false, ebiten.KeyW, ebiten.KeyArrowUp) {
-		driveY -= 1
-	}
-	if isAnyOfKeysPressed(false, ebiten.KeyS, ebiten.KeyArrowDown) {
-		driveY += 1
-	}
-	if isAnyOfKeysPressed(false, ebiten.KeyA, ebiten.KeyArrowLeft) {
-		driveX -= 1
-	}
-	if isAnyOfKeysPressed(false, ebiten.KeyD, ebiten.KeyArrowRight) {
-		driveX += 1
-	}
-	if isAnyOfKeysPressed(true, ebiten.KeySpace) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButton2) || fish.game.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightBottom) {
+	frame := fish.Input.Poll(fish)
+	driveX, driveY = frame.DriveX, frame.DriveY
+	if frame.Plane && fish.PlaneShift == 0 {
 		fish.SwitchPlane()
+		fish.game.Notify(GameEvent{Kind: eventPlaneSwitch, Player: fish})
 	}
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButton0) {
-		jx, jy := ebiten.CursorPosition()
-		mx, my := float64(jx)-fish.X, float64(jy)-fish.Y
-		if math.Hypot(mx, my) >= fish.HalfHeight {
-			driveX, driveY = mx, my
-		}
-	}
-	if fish.game.debugEnabled {
+	if fish.game.debugEnabled && fish.Index == 0 {
 
 		if isAnyOfKeysPressed(false, ebiten.KeyPageUp) {
-			fish.SetSize(fish.Size + 1)
+			fish.Grow(1)
 		}
 		if isAnyOfKeysPressed(false, ebiten.KeyPageDown) {
-			fish.SetSize(fish.Size - 1)
+			fish.Grow(-1)
 		}
 		if isAnyOfKeysPressed(false, ebiten.KeyDelete) {
 			fish.Die()
 		}
 	}
 
-	if ebiten.IsStandardGamepadLayoutAvailable(fish.game.gamepadId) {
-		gx, gy := ebiten.StandardGamepadAxisValue(fish.game.gamepadId, ebiten.StandardGamepadAxisLeftStickHorizontal), ebiten.StandardGamepadAxisValue(fish.game.gamepadId, ebiten.StandardGamepadAxisLeftStickVertical)
-		if math.Hypot(gx, gy) > 0.25 {
-			driveX, driveY = gx, gy
-		}
-	}
 	if driveAbs := math.Hypot(driveX, driveY); driveAbs > 1 {
 		driveX, driveY = driveX/driveAbs, driveY/driveAbs
 	}
@@ -480,17 +548,25 @@ func (fish *PlayerFish) ReadInput() (driveX, driveY float64) {
 		fish.FacingLeft = driveX < 0
 		fish.GraphUpdated = true
 	}
+	if frame.Dash {
+		fish.Dash(driveX, driveY)
+	}
 	return
 }
 
 func (fish *PlayerFish) Rebound(vertical bool) {
 	switch vertical {
 	case false:
+		// A fish wider than a narrow ocean has no room to bounce, so it stays in the middle rather than flipping between the edges.
+		if fish.HalfWidth*2 >= fish.game.worldWidth {
+			fish.X, fish.SpeedX = fish.game.worldWidth/2, 0
+			return
+		}
 		fish.X -= fish.SpeedX
 		fish.SpeedX *= -0.5
 	case true:
 		if fish.Dead {
-			fish.game.GameOver()
+			fish.game.CheckGameOver()
 			return
 		}
 		fish.Y -= fish.SpeedY
@@ -501,17 +577,23 @@ func (fish *PlayerFish) Rebound(vertical bool) {
 func (fish *PlayerFish) Reset() {
 	fish.Dead = false
 	fish.Plane = 0
+	fish.PlaneShift = 0
 	fish.SetSize(10)
-	fish.X = float64(fish.game.screenWidth) / 2
-	fish.Y = float64(fish.game.screenHeight) / 2
+	fish.X = fish.game.worldWidth/2 + fish.game.viewWidth*(float64(fish.Index+1)/float64(len(fish.game.players)+1)-0.5)
+	fish.Y = fish.game.worldHeight / 2
 	fish.SpeedX, fish.SpeedY = 0, 0
-	fish.FrictionCoefficient = 1
+	fish.DriveX, fish.DriveY = 0, 0
+	fish.DashCooldown, fish.Glow = 0, 0
+	fish.Combo, fish.ComboTimer = 0, 0
+	fish.Score, fish.Eaten = 0, 0
+	fish.Stage = getGrowthStage(fish.Size)
+	fish.ApplyStage(false)
 	fish.GraphUpdated = true
 }
 
 func (fish *Fish) ResizeSprite() {
-	fish.Scale = math.Pow(0.75, fish.Plane) * fish.Size / 64
-	actualSize := fish.Image.Bounds()
+	fish.Scale = math.Pow(0.75, fish.VisualPlane()) * fish.Size / 64
+	actualSize := fish.Mask.Bounds()
 	fish.HalfWidth, fish.HalfHeight = fish.Scale*float64(actualSize.Dx())/2, fish.Scale*float64(actualSize.Dy())/2
 	fish.GraphUpdated = true
 }
@@ -532,8 +614,8 @@ func (fish *Fish) Swim(driveX, driveY float64) {
 	if fish.Dead {
 		accX, accY = 0, -0.2
 	} else {
-		accX = driveX*fish.game.playerAcceleration + fish.FrictionCoefficient*fish.SpeedX*fish.game.playerDeceleration
-		accY = driveY*fish.game.playerAcceleration + fish.FrictionCoefficient*fish.SpeedY*fish.game.playerDeceleration
+		accX = driveX*fish.game.playerAcceleration*fish.AccelerationCoefficient + fish.FrictionCoefficient*fish.SpeedX*fish.game.playerDeceleration
+		accY = driveY*fish.game.playerAcceleration*fish.AccelerationCoefficient + fish.FrictionCoefficient*fish.SpeedY*fish.game.playerDeceleration
 	}
 	fish.SpeedX += accX
 	fish.SpeedY += accY
@@ -541,32 +623,54 @@ func (fish *Fish) Swim(driveX, driveY float64) {
 	fish.Y += fish.SpeedY
 }
 
+// SwitchPlane starts the shift from wherever the fish appears to be, so switching again mid-shift turns it around smoothly.
+// Players can't do that, since a fish can't collide while shifting and could otherwise dodge everything by switching nonstop.
 func (fish *Fish) SwitchPlane() {
+	fish.PrevPlane = fish.VisualPlane()
 	fish.Plane = math.Mod(fish.Plane+1, fish.game.planeCount)
+	if fish.Plane != fish.PrevPlane {
+		fish.PlaneShift = planeShiftFrames
+	}
 	fish.ResizeSprite()
 }
 
+// VisualPlane is the plane the fish appears to be in, somewhere between the previous and the current one mid-switch.
+func (fish *Fish) VisualPlane() float64 {
+	if fish.PlaneShift <= 0 {
+		return fish.Plane
+	}
+	progress := 1 - fish.PlaneShift/planeShiftFrames
+	return fish.PrevPlane + (fish.Plane-fish.PrevPlane)*progress
+}
+
 type MenuItem struct {
 	title    string
 	x, y, h  float64
 	selector int
-	fontFace *text.GoTextFace
+	fontFace text.Face
 	values   []float64
 	titles   []string
 }
 
-func (m *MenuItem) Draw(screen *ebiten.Image, active bool) {
+// Draw translates the item as it goes and squeezes it when the translation is too long for the screen.
+func (m *MenuItem) Draw(g *Game, active bool) {
 
-	itemText := m.title
+	itemText := g.tr(m.title)
 	if len(m.titles) > 0 {
-		itemText += ": " + m.titles[m.selector]
+		itemText += ": " + g.tr(m.titles[m.selector])
 	}
 	op := &text.DrawOptions{}
 	if active {
 		op.ColorScale.ScaleWithColor(color.RGBA{255, 128, 0, 255})
 	}
-	op.GeoM.Translate(m.x, m.y+(m.h/2)-(m.fontFace.Size/2))
-	text.Draw(screen, itemText, m.fontFace, op)
+	width, height := text.Measure(itemText, m.fontFace, 0)
+	scale := 1.0
+	if room := 0.95*g.screenWidth - m.x; width > room {
+		scale = room / width
+	}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(m.x, m.y+(m.h-scale*height)/2)
+	text.Draw(g.screen, itemText, m.fontFace, op)
 }
 
 func (m *MenuItem) DetectHover() bool {
@@ -602,49 +706,105 @@ func (m *MenuItem) GetValue() float64 {
 }
 
 type Game struct {
+	achievements         Achievements
 	activeMenuIndex      int
 	background           color.Color
+	bestCombo            float64
+	bindings             Bindings
+	camera               Camera
+	capturingAction      int
+	controlCalls         chan controlCall
+	controlsMenu         []MenuItem
+	controlsMessage      string
+	deathCause           *DeathCause
 	debugEnabled         bool
+	demo                 bool
+	display              float64
 	eaten                float64
-	fancyFontSource      *text.GoTextFaceSource
+	fancyFontSources     []*text.GoTextFaceSource
 	fishPerPlane         float64
 	fishSpeedModifier    float64
 	fishSizeCap          float64
 	fishReactionsEnabled bool
-	fishStaticArray      [50]Fish
+	fishStaticArray      [maxFishPerPlane*maxPlanes + maxSpawnedFish]Fish
 	fish                 []Fish
 	fontSizes            map[string]float64
+	gameOverTip          string
 	gamepadId            ebiten.GamepadID
+	gamepadIds           []ebiten.GamepadID
 	gameState            int
+	hasGamepad           bool
+	headless             bool
 	highScore            float64
+	idleTicks            int
+	language             *Language
+	lobbyAddress         string
+	lobbyEditing         bool
+	lobbyMenu            []MenuItem
 	mainMenu             []MenuItem
 	menuHidden           bool
+	metrics              *Metrics
+	modErrors            []string
+	mods                 []*ModPack
 	mostEaten            float64
+	net                  *NetSession
+	netAutopilot         bool
+	netDelay             int
+	notice               string
+	noticeTimer          float64
+	oceanSize            float64
 	optionsMenu          []MenuItem
+	particleStaticArray  [maxParticles]Particle
+	particles            []Particle
 	paused               bool
-	plainFontSource      *text.GoTextFaceSource
+	plainFontSources     []*text.GoTextFaceSource
 	planeCount           float64
 	playerAcceleration   float64
+	playerCollisions     float64
+	playerCount          float64
 	playerDeceleration   float64
-	playerFish           PlayerFish
+	playerStaticArray    [maxPlayers]PlayerFish
+	players              []PlayerFish
+	popups               []ScorePopup
 	preloadedImages      map[string]image.Image
 	prevCurX             int
 	prevCurY             int
-	randomQuote          string
+	rng                  *rand.Rand
 	score                float64
+	sound                *Sound
+	spriteColors         map[string]color.NRGBA
+	statistics           Statistics
+	spectators           *SpectatorServer
+	species              []Species
 	screen               *ebiten.Image
 	screenHeight         float64
 	screenWidth          float64
+	tips                 TipProgress
 	totalFishCount       int
+	viewHeight           float64
+	viewWidth            float64
+	worldHeight          float64
+	worldWidth           float64
 }
 
 func (g *Game) ApplyOptions() {
-	g.planeCount = g.optionsMenu[0].GetValue()
-	g.fishPerPlane = g.optionsMenu[1].GetValue()
-	g.fishSpeedModifier = g.optionsMenu[2].GetValue()
-	g.fishSizeCap = g.optionsMenu[3].GetValue()
-	g.fishReactionsEnabled = g.optionsMenu[4].GetValue() == 1
-	ebiten.SetFullscreen(g.optionsMenu[5].GetValue() == 1)
+	g.planeCount = g.optionsMenu[optionPlanes].GetValue()
+	g.fishPerPlane = g.optionsMenu[optionFishAmount].GetValue()
+	g.fishSpeedModifier = g.optionsMenu[optionFishSpeed].GetValue()
+	g.fishSizeCap = g.optionsMenu[optionFishSize].GetValue()
+	g.fishReactionsEnabled = g.optionsMenu[optionFishReactions].GetValue() == 1
+	g.SetOceanSize(g.optionsMenu[optionOceanSize].GetValue())
+	if !g.headless {
+		ebiten.SetFullscreen(g.optionsMenu[optionFullscreen].GetValue() == 1)
+	}
+	g.display = g.optionsMenu[optionDisplay].GetValue()
+	g.playerCount = g.optionsMenu[optionPlayers].GetValue()
+	g.playerCollisions = g.optionsMenu[optionPlayerCollisions].GetValue()
+	g.ApplyLanguage()
+	if g.sound != nil {
+		g.sound.SetVolume(g.optionsMenu[optionVolume].GetValue(), g.optionsMenu[optionMute].GetValue() == 1)
+	}
+	g.GeneratePlayers()
 	g.GenerateFish()
 	for i, _ := range g.fish {
 		g.fish[i].Randomize()
@@ -652,104 +812,40 @@ func (g *Game) ApplyOptions() {
 }
 
 func (g *Game) CreateMenus() {
-	face := g.GetFontFace("big", true)
-	faceOpt := g.GetFontFace("biggish", true)
-	x := 0.2 * g.screenWidth
-	y := 0.35 * g.screenHeight
-	h := 0.2 * g.screenHeight
 	mainMenuItems := []string{
-		"PLAY", "Options", "Quit",
+		"PLAY", "Online", "Achievements", "Statistics", "Options", "Quit",
 	}
 	for _, title := range mainMenuItems {
-		g.mainMenu = append(g.mainMenu, MenuItem{
-			title:    title,
-			x:        x,
-			y:        y,
-			h:        h,
-			fontFace: face,
-		})
-		y += h
-	}
-	x = 0.2 * g.screenWidth
-	y = 0.075 * g.screenHeight
-	h = 0.12 * g.screenHeight
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title:    "Game planes",
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-		selector: 1,
-		titles:   []string{"1", "2"},
-		values:   []float64{1, 2},
-	})
-	y += h
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title:    "Fish amount",
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-		selector: 2,
-		titles:   []string{"scarce", "less", "normal", "more", "swarm"},
-		values:   []float64{5, 10, 15, 20, 25},
-	})
-	y += h
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title: "Fish speed",
-
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-		selector: 1,
-		titles:   []string{"slow", "normal", "fast", "frenzy"},
-		values:   []float64{0.5, 1, 1.5, 2},
-	})
-	y += h
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title:    "Fish max size",
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-		selector: 0,
-		titles:   []string{"big", "bigger", "biggest"},
-		values:   []float64{45, 60, 75},
-	})
-	y += h
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title:    "Fish reactions",
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-		selector: 1,
-		titles:   []string{"off", "on"},
-		values:   []float64{0, 1},
-	})
-	y += h
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title:    "Fullscreen",
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-		selector: 1,
-		titles:   []string{"no", "yes"},
-		values:   []float64{0, 1},
-	})
-	y += h
-	g.optionsMenu = append(g.optionsMenu, MenuItem{
-		title:    "Back",
-		x:        x,
-		y:        y,
-		h:        h,
-		fontFace: faceOpt,
-	})
+		g.mainMenu = append(g.mainMenu, MenuItem{title: title})
+	}
+	options := []MenuItem{
+		{title: "Game planes", selector: 1, titles: []string{"1", "2"}, values: []float64{1, 2}},
+		{title: "Fish amount", selector: 2, titles: []string{"scarce", "less", "normal", "more", "swarm"}, values: []float64{5, 10, 15, 20, 25}},
+		{title: "Fish speed", selector: 1, titles: []string{"slow", "normal", "fast", "frenzy"}, values: []float64{0.5, 1, 1.5, 2}},
+		{title: "Fish max size", selector: 0, titles: []string{"big", "bigger", "biggest"}, values: []float64{45, 60, 75}},
+		{title: "Fish reactions", selector: 1, titles: []string{"off", "on"}, values: []float64{0, 1}},
+		{title: "Ocean size", selector: 0, titles: []string{"screen", "large", "huge"}, values: []float64{1, 2, 3}},
+		{title: "Fullscreen", selector: 1, titles: []string{"no", "yes"}, values: []float64{0, 1}},
+		{title: "Display", selector: displayLetterbox, titles: []string{"letterbox", "extend"}, values: []float64{displayLetterbox, displayExtend}},
+		{title: "Players", selector: 0, titles: []string{"1", "2", "3", "4"}, values: []float64{1, 2, 3, 4}},
+		{title: "Player collisions", selector: 0, titles: []string{"ignore", "bigger eats", "bump"}, values: []float64{playerCollisionsIgnore, playerCollisionsEat, playerCollisionsBump}},
+		{title: "Language", selector: int(systemLanguage())},
+		{title: "Volume", selector: 3, titles: []string{"25%", "50%", "75%", "100%"}, values: []float64{0.25, 0.5, 0.75, 1}},
+		{title: "Mute", selector: 0, titles: []string{"no", "yes"}, values: []float64{0, 1}},
+		{title: "Controls"},
+		{title: "Back"},
+	}
+	for i, lang := range languages {
+		options[optionLanguage].titles = append(options[optionLanguage].titles, lang.Name)
+		options[optionLanguage].values = append(options[optionLanguage].values, float64(i))
+	}
+	g.optionsMenu = append(g.optionsMenu, options...)
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	if g.metrics != nil {
+		defer g.ObserveDraw(time.Now())
+	}
 	g.screen = screen
 	screen.Fill(g.background)
 
@@ -762,9 +858,20 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.DrawMenu()
 	case gameOptionsMenu:
 		g.DrawOptions()
+	case gameControlsMenu:
+		g.DrawControls()
 	case gameVictory:
 		g.DrawVictory()
+	case gameLobby:
+		g.DrawLobby()
+	case gameAchievements:
+		g.DrawAchievements()
+	case gameStatistics:
+		g.DrawStatistics()
 	}
+	g.DrawNetStatus()
+	g.DrawNotice()
+	g.DrawToast()
 }
 
 func (g *Game) DrawAllNpcFish() {
@@ -772,33 +879,56 @@ func (g *Game) DrawAllNpcFish() {
 }
 
 func (g *Game) DrawGame() {
-	if !g.playerFish.Dead {
+	if !g.AllPlayersDead() {
 		g.DrawAllNpcFish()
 	}
-	g.playerFish.Draw()
+	g.DrawPlayers()
+	g.DrawPlayerLabels()
+	g.DrawPopups()
+	if g.demo {
+		g.DrawDemoBanner()
+	}
 	if g.debugEnabled {
+		player := &g.players[0]
 		ebitenutil.DebugPrint(g.screen, fmt.Sprintf("Fish position (X Y): %0.2f %0.2f Fish Speed (X Y): %0.5f %0.5f Size: %0.0f axis: %0.2f",
-			g.playerFish.X, g.playerFish.Y, g.playerFish.SpeedX, g.playerFish.SpeedY, g.playerFish.Size, ebiten.StandardGamepadAxisValue(g.gamepadId, ebiten.StandardGamepadAxisLeftStickHorizontal)))
+			player.X, player.Y, player.SpeedX, player.SpeedY, player.Size, ebiten.StandardGamepadAxisValue(g.gamepadId, ebiten.StandardGamepadAxisLeftStickHorizontal)))
 	}
 }
 
-func (g *Game) DrawGameOver() {
-
+func (g *Game) DrawEvolution(player *PlayerFish) {
 	op := &text.DrawOptions{}
+	face := g.GetFontFace("medium", true)
+	label := strings.ToUpper(g.tr(growthStages[player.Stage].Name)) + "!"
+	width, _ := text.Measure(label, face, 0)
+	x, y := g.ToScreen(player.X, player.Y-player.HalfHeight)
+	op.GeoM.Translate(x-width/2, y-g.Font("medium")*1.5)
+	op.ColorScale.ScaleAlpha(float32(player.Glow / evolutionFrames))
+	text.Draw(g.screen, label, face, op)
+}
+
+func (g *Game) DrawGameOver() {
 
-	op.GeoM.Translate(0.5*g.screenWidth-g.Font("small")*float64(len(g.randomQuote))/3.6, 0.4*g.screenHeight)
-	text.Draw(g.screen, g.randomQuote, g.GetFontFace("small", true), op)
+	g.DrawAllParticles()
+	g.DrawDeathCause()
+	face := g.GetFontFace("small", true)
+	lines := wrapText(g.tr(g.gameOverTip), face, 0.8*g.screenWidth)
+	for i, line := range lines {
+		g.DrawCentered(line, face, 0.4*g.screenHeight-float64(len(lines)-1-i)*1.2*g.Font("small"), nil)
+	}
 	g.DrawHiScores()
 	g.DrawScores()
+	g.DrawPlayerScores()
 }
 
 func (g *Game) DrawHiScores() {
 	op := &text.DrawOptions{}
 	face := g.GetFontFace("medium", true)
 	op.GeoM.Translate(0.1*g.screenWidth, 0.02*g.screenHeight)
-	text.Draw(g.screen, fmt.Sprintf("BEST EATING SPREE: %0.0f", g.mostEaten), face, op)
+	text.Draw(g.screen, g.tr("BEST EATING SPREE: %0.0f", g.mostEaten), face, op)
 	op.GeoM.Translate(0.6*g.screenWidth, 0)
-	text.Draw(g.screen, fmt.Sprintf("HI-SCORE: %0.0f", g.highScore), face, op)
+	text.Draw(g.screen, g.tr("HI-SCORE: %0.0f", g.highScore), face, op)
+	op.GeoM.Translate(-0.6*g.screenWidth, 1.2*g.Font("medium"))
+	text.Draw(g.screen, g.tr("BEST COMBO: x%0.2f", comboMultiplier(g.bestCombo)), g.GetFontFace("small", true), op)
 }
 
 func (g *Game) DrawMenu() {
@@ -815,12 +945,13 @@ func (g *Game) DrawMenu() {
 		text.Draw(g.screen, title, logoFace, logoOp)
 
 		for i, menuItem := range g.mainMenu {
-			menuItem.Draw(g.screen, i == g.activeMenuIndex)
+			menuItem.Draw(g, i == g.activeMenuIndex)
 		}
+		g.DrawModErrors()
 
 		text.Draw(g.screen, "<", plainFace, footerOp)
 		footerOp.GeoM.Translate(0.8*g.screenWidth, 0)
-		text.Draw(g.screen, "by Dmitriy Lisovin (2024)", g.GetFontFace("small", false), footerOp)
+		text.Draw(g.screen, g.tr("by Dmitriy Lisovin (2024)"), g.GetFontFace("small", false), footerOp)
 
 	} else {
 		text.Draw(g.screen, ">", plainFace, footerOp)
@@ -830,12 +961,12 @@ func (g *Game) DrawMenu() {
 func (g *Game) DrawOptions() {
 	g.DrawAllNpcFish()
 	for i, menuItem := range g.optionsMenu {
-		menuItem.Draw(g.screen, i == g.activeMenuIndex)
+		menuItem.Draw(g, i == g.activeMenuIndex)
 	}
 }
 
 func (g *Game) DrawPlanesRecursive(fishes []Fish, count, plane int) {
-	if plane < 0 || count <= 0 {
+	if plane < 0 {
 		return
 	}
 	otherPlaneFishes := []Fish{}
@@ -849,34 +980,38 @@ func (g *Game) DrawPlanesRecursive(fishes []Fish, count, plane int) {
 			otherCount++
 		}
 	}
+	g.DrawParticles(plane)
 	g.DrawPlanesRecursive(otherPlaneFishes, otherCount, plane-1)
 }
 
+func (g *Game) DrawPlayers() {
+	for i := range g.players {
+		g.players[i].Draw()
+		if g.players[i].Glow > 0 && !g.players[i].Dead {
+			g.DrawEvolution(&g.players[i])
+		}
+	}
+}
+
 func (g *Game) DrawScores() {
 	op := &text.DrawOptions{}
 	face := g.GetFontFace("medium", true)
 	op.GeoM.Translate(0.4*g.screenWidth, 0.5*g.screenHeight)
-	text.Draw(g.screen, fmt.Sprintf("FISH EATEN: %0.0f", g.eaten), face, op)
+	text.Draw(g.screen, g.tr("FISH EATEN: %0.0f", g.eaten), face, op)
 	op.GeoM.Translate(0.03*g.screenWidth, 0.1*g.screenHeight)
-	text.Draw(g.screen, fmt.Sprintf("SCORE: %0.0f", g.score), face, op)
+	text.Draw(g.screen, g.tr("SCORE: %0.0f", g.score), face, op)
 }
 
 func (g *Game) DrawVictory() {
 	g.DrawAllNpcFish()
-	g.playerFish.Draw()
-	op := &text.DrawOptions{}
-	face := &text.GoTextFace{
-		Source: g.fancyFontSource,
-		Size:   g.Font("medium"),
-	}
-	op.GeoM.Translate(0.375*g.screenWidth, 0.2*g.screenHeight)
-	text.Draw(g.screen, "CONGRATULATIONS!", face, op)
-	op.GeoM.Translate(-0.225*g.screenWidth, 0.1*g.screenHeight)
-	text.Draw(g.screen, "You have become the biggest fish in the ocean!", face, op)
-	op.GeoM.Translate(0.05*g.screenWidth, 0.1*g.screenHeight)
-	text.Draw(g.screen, "Now you can eat anyone with impunity.", face, op)
+	g.DrawPlayers()
+	face := g.GetFontFace("medium", true)
+	for i, line := range []string{"CONGRATULATIONS!", "You have become the biggest fish in the ocean!", "Now you can eat anyone with impunity."} {
+		g.DrawCentered(g.tr(line), face, (0.2+0.1*float64(i))*g.screenHeight, nil)
+	}
 	g.DrawHiScores()
 	g.DrawScores()
+	g.DrawPlayerScores()
 }
 
 func (g *Game) End(gameState int) {
@@ -889,41 +1024,56 @@ func (g *Game) Font(i string) float64 {
 
 func (g *Game) GameOver() {
 	g.End(gameOver)
-	g.randomQuote = quotes[rand.Intn(len(quotes))]
+	g.Notify(GameEvent{Kind: eventGameOver})
+	g.gameOverTip = g.NextTip()
 
 }
 
 func (g *Game) GameCycle() error {
-	if !g.paused {
-		for i, _ := range g.fish {
-			g.fish[i].Move()
+	if g.net != nil {
+		return g.NetGameCycle()
+	}
+	if g.demo {
+		g.idleTicks++
+		if g.IsAnyInput() {
+			g.StopDemo()
+			return nil
 		}
-		g.playerFish.Move()
 	}
-	if !g.playerFish.Dead {
-		g.GetBackgroundColor(g.playerFish.Y)
-		if isAnyOfKeysPressed(true, ebiten.KeyP, ebiten.KeyPause, ebiten.KeyEnter) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonCenterRight) {
+	if !g.paused {
+		g.SimulateTick()
+	}
+	if !g.AllPlayersDead() && !g.demo {
+		g.GetBackgroundColor(g.LeadPlayer().Y)
+		if g.IsActionPressed(actionPause, true) {
 			g.paused = !g.paused
 		}
-		if isAnyOfKeysPressed(true, ebiten.KeyEscape) {
+		if g.IsActionPressed(actionBack, true) {
 			if g.paused {
 				g.GoToMenu(false)
 			} else {
 				g.paused = true
 			}
 		}
-		if g.paused && g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightRight) {
-			g.GoToMenu(false)
+		if g.paused {
+			g.tips.Paused = true
 		}
 	}
 	return nil
 }
 
 func (g *Game) GameOverCycle() error {
-	if isAnyOfKeysPressed(true, ebiten.KeySpace, ebiten.KeyEnter) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButton0) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButton2) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightBottom, ebiten.StandardGamepadButtonCenterRight) {
+	if g.net != nil {
+		return g.NetRoundOverCycle()
+	}
+	if g.demo {
+		g.StopDemo()
+		return nil
+	}
+	if g.IsActionPressed(actionConfirm, true) || g.IsActionPressed(actionPlane, true) {
 		g.Restart()
 	}
-	if isAnyOfKeysPressed(true, ebiten.KeyEscape) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightRight) {
+	if g.IsActionPressed(actionBack, true) {
 		g.GoToMenu(true)
 	}
 
@@ -934,32 +1084,45 @@ func (g *Game) GenerateFish() {
 	g.totalFishCount = int(g.fishPerPlane * g.planeCount)
 	g.fish = g.fishStaticArray[0:g.totalFishCount]
 	for i := 0; i < g.totalFishCount; i++ {
-		g.fish[i].Init(g, getFishType(float64(i), float64(g.totalFishCount)))
+		g.fish[i].Init(g, g.getFishSpecies(float64(i), float64(g.totalFishCount)))
 	}
+	g.SeedPlankton()
 }
 
 func (g *Game) GetBackgroundColor(y float64) {
-	max := float64(g.screenHeight)
+	max := g.worldHeight
 	r, gr, b := getColorComponentByDepth(y, max/3, 128), getColorComponentByDepth(y, max/2, 255), getColorComponentByDepth(y, max, 192)
 	g.background = color.RGBA{uint8(r), uint8(gr), uint8(b), 128}
 	return
 }
 
-func (g *Game) GetFontFace(sizeIndex string, fancy bool) (face *text.GoTextFace) {
-	face = &text.GoTextFace{
-		Source: g.plainFontSource,
-		Size:   g.Font(sizeIndex),
-	}
+func (g *Game) GetFontFace(sizeIndex string, fancy bool) text.Face {
+	return g.FontFace(g.Font(sizeIndex), fancy)
+}
+
+// FontFace chains the font with its fallbacks, so glyphs it lacks, like Cyrillic in AquaWow, still show up.
+func (g *Game) FontFace(size float64, fancy bool) text.Face {
+	sources := g.plainFontSources
 	if fancy {
-		face.Source = g.fancyFontSource
+		sources = g.fancyFontSources
 	}
-	return
+	faces := make([]text.Face, len(sources))
+	for i, source := range sources {
+		faces[i] = &text.GoTextFace{Source: source, Size: size}
+	}
+	face, err := text.NewMultiFace(faces...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return face
 }
 
 func (g *Game) GoToMenu(generate bool) {
+	g.LeaveRun()
 	g.gameState = gameMenu
 	g.menuHidden = false
 	g.activeMenuIndex = 0
+	g.ResetCamera()
 	if generate {
 		g.GenerateFish()
 		for i, _ := range g.fish {
@@ -984,55 +1147,52 @@ func (g *Game) HasMouseMoved() (hasMoved bool) {
 }
 
 func (g *Game) isAnyGamepadButtonsPressed(just bool, buttons ...ebiten.StandardGamepadButton) (pressed bool) {
-	var method func(id ebiten.GamepadID, button ebiten.StandardGamepadButton) bool
-	if just {
-		method = inpututil.IsStandardGamepadButtonJustPressed
-	} else {
-		method = ebiten.IsStandardGamepadButtonPressed
+	if g.hasGamepad && isGamepadButtonsPressed(g.gamepadId, just, buttons...) {
+		return true
 	}
-
-	if ebiten.IsStandardGamepadLayoutAvailable(g.gamepadId) {
-		for _, button := range buttons {
-			if method(g.gamepadId, button) {
-				pressed = true
-			}
+	for i := range g.players {
+		if id, ok := g.players[i].GamepadID(); ok && id != g.gamepadId && isGamepadButtonsPressed(id, just, buttons...) {
+			return true
 		}
-
 	}
-	return
-}
-
-func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidthVar, screenHeightVar int) {
-	return screenWidth, screenHeight
+	return false
 }
 
 func (g *Game) MenuButtonDown() bool {
-	return isAnyOfKeysPressed(true, ebiten.KeyArrowDown, ebiten.KeyS) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonLeftBottom)
+	return g.IsActionPressed(actionDown, true)
 }
 
 func (g *Game) MenuButtonLeft() bool {
-	return isAnyOfKeysPressed(true, ebiten.KeyA, ebiten.KeyArrowLeft) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonLeftLeft)
+	return g.IsActionPressed(actionLeft, true)
 }
 
 func (g *Game) MenuButtonRight() bool {
-	return isAnyOfKeysPressed(true, ebiten.KeyD, ebiten.KeyArrowRight) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonLeftRight)
+	return g.IsActionPressed(actionRight, true)
 }
 
 func (g *Game) MenuButtonUp() bool {
-	return isAnyOfKeysPressed(true, ebiten.KeyArrowUp, ebiten.KeyW) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonLeftTop)
+	return g.IsActionPressed(actionUp, true)
 }
 
 func (g *Game) MenuCycle() error {
 	for i, _ := range g.fish {
 		g.fish[i].Move()
 	}
-	if x, y := ebiten.CursorPosition(); (float64(y) >= 0.9*g.screenHeight && float64(x) < 0.03*g.screenWidth && inpututil.IsMouseButtonJustPressed(ebiten.MouseButton0)) || isAnyOfKeysPressed(true, ebiten.KeyH) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonCenterLeft) {
+	if x, y := ebiten.CursorPosition(); (float64(y) >= 0.9*g.screenHeight && float64(x) < 0.03*g.screenWidth && inpututil.IsMouseButtonJustPressed(ebiten.MouseButton0)) || g.IsActionPressed(actionHideMenu, true) {
 		g.menuHidden = !g.menuHidden
+		g.idleTicks = 0
 		return nil
 	}
+	// With the menu hidden the ocean is meant to be watched, so it never turns into the demo.
 	if g.menuHidden {
 		return nil
 	}
+	if g.IsAnyInput() {
+		g.idleTicks = 0
+	} else if g.idleTicks++; g.idleTicks > attractModeDelay {
+		g.StartDemo()
+		return nil
+	}
 	if g.MenuButtonDown() {
 		g.activeMenuIndex = int(math.Min(float64(g.activeMenuIndex+1), float64(len(g.mainMenu)-1)))
 	}
@@ -1046,13 +1206,19 @@ func (g *Game) MenuCycle() error {
 			}
 		}
 	}
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButton0) || isAnyOfKeysPressed(true, ebiten.KeyEnter, ebiten.KeySpace) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightBottom, ebiten.StandardGamepadButtonCenterRight) {
+	if g.IsActionPressed(actionConfirm, true) {
 		switch g.activeMenuIndex {
-		case 0:
+		case menuPlay:
 			g.Start()
-		case 1:
+		case menuOnline:
+			g.GoToLobby()
+		case menuAchievements:
+			g.GoToAchievements()
+		case menuStatistics:
+			g.GoToStatistics()
+		case menuOptions:
 			g.GoToOptions()
-		case 2:
+		case menuQuit:
 			os.Exit(0)
 		}
 	}
@@ -1065,7 +1231,7 @@ func (g *Game) OptionsCycle() error {
 	for i, _ := range g.fish {
 		g.fish[i].Move()
 	}
-	if isAnyOfKeysPressed(true, ebiten.KeyEscape) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightRight) {
+	if g.IsActionPressed(actionBack, true) {
 		g.GoToMenu(true)
 	}
 	if g.MenuButtonDown() {
@@ -1082,7 +1248,7 @@ func (g *Game) OptionsCycle() error {
 			}
 		}
 	}
-	if g.activeMenuIndex != backIndex {
+	if len(g.optionsMenu[g.activeMenuIndex].values) > 0 {
 		if g.MenuButtonRight() {
 			if g.optionsMenu[g.activeMenuIndex].ShiftRight() {
 				g.ApplyOptions()
@@ -1094,8 +1260,10 @@ func (g *Game) OptionsCycle() error {
 			}
 		}
 	}
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButton0) || isAnyOfKeysPressed(true, ebiten.KeyEnter, ebiten.KeySpace) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightBottom) {
+	if g.IsActionPressed(actionConfirm, true) {
 		switch {
+		case g.activeMenuIndex == optionControls:
+			g.GoToControls()
 		case g.activeMenuIndex < backIndex:
 			g.optionsMenu[g.activeMenuIndex].CycleRight()
 			g.ApplyOptions()
@@ -1109,10 +1277,15 @@ func (g *Game) OptionsCycle() error {
 func (g *Game) Restart() {
 	g.gameState = gameRunning
 	g.score, g.eaten = 0, 0
+	g.popups = g.popups[:0]
+	for i := range g.players {
+		g.players[i].Reset()
+	}
+	g.ResetCamera()
 	for i, _ := range g.fish {
 		g.fish[i].Randomize()
 	}
-	g.playerFish.Reset()
+	g.Notify(GameEvent{Kind: eventStart})
 }
 
 func (g *Game) SetDefaultOptions() {
@@ -1122,28 +1295,128 @@ func (g *Game) SetDefaultOptions() {
 	g.fishPerPlane = 15
 	g.fishSizeCap = 45
 	g.fishSpeedModifier = 1.0
+	g.playerCount = 1
+	g.playerCollisions = playerCollisionsIgnore
 	g.playerAcceleration = 0.5
 	g.playerDeceleration = -0.025
 }
 
+func (g *Game) SetOption(option int, value float64) error {
+	item := &g.optionsMenu[option]
+	for i, v := range item.values {
+		if v == value {
+			item.selector = i
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: unsupported value %v, expected one of %v", item.title, value, item.values)
+}
+
+// SetFontsSizes scales the text with the screen height, or with the width on screens narrower than 16:9.
 func (g *Game) SetFontsSizes() {
 	clear(g.fontSizes)
-	g.fontSizes["logo"] = 0.15 * g.screenHeight
-	g.fontSizes["big"] = 0.1 * g.screenHeight
-	g.fontSizes["biggish"] = 0.08 * g.screenHeight
-	g.fontSizes["medium"] = 0.05 * g.screenHeight
-	g.fontSizes["small"] = 0.03 * g.screenHeight
+	unit := math.Min(g.screenHeight, g.screenWidth*screenHeight/screenWidth)
+	g.fontSizes["logo"] = 0.15 * unit
+	g.fontSizes["big"] = 0.1 * unit
+	g.fontSizes["biggish"] = 0.08 * unit
+	g.fontSizes["medium"] = 0.05 * unit
+	g.fontSizes["small"] = 0.03 * unit
 
 	return
 }
 
+func (g *Game) Setup() {
+	g.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	g.SetDefaultOptions()
+	g.screenWidth, g.screenHeight = screenWidth, screenHeight
+	g.fontSizes = make(map[string]float64)
+	g.SetFontsSizes()
+	g.preloadedImages = map[string]image.Image{
+		"player":   preloadImage(playerImage),
+		"bass":     preloadImage(bassImage),
+		"shark":    preloadImage(sharkImage),
+		"puffer":   preloadImage(pufferImage),
+		"goldfish": preloadImage(goldfishImage),
+		"jelly":    preloadImage(jellyImage),
+	}
+	g.LoadMods()
+	g.ApplyModSprites()
+	for _, stage := range growthStages {
+		if _, ok := g.preloadedImages[stage.Sprite]; !ok {
+			g.preloadedImages[stage.Sprite] = recolorImage(g.preloadedImages["player"], stage.Hue, stage.Saturation, stage.Value)
+		}
+	}
+	g.ApplyModSpecies()
+	g.viewWidth, g.viewHeight = screenWidth, screenHeight
+	g.SetOceanSize(1)
+	g.GetBackgroundColor(g.worldHeight / 2)
+	g.LoadBindings()
+	g.LoadAchievements()
+	g.LoadStatistics()
+	g.LoadRecords()
+	g.LoadTips()
+	g.ApplyModTips()
+	fallbackFont := loadFont(anonymousPro)
+	g.plainFontSources = g.ModFontSources("plain", []*text.GoTextFaceSource{loadFont(fixedsys), fallbackFont})
+	g.fancyFontSources = g.ModFontSources("fancy", []*text.GoTextFaceSource{loadFont(aquawow), fallbackFont})
+	g.ValidateModFonts()
+	loadLanguages()
+	g.language = &languages[0]
+	g.GeneratePlayers()
+	g.CreateMenus()
+	g.ApplyLanguage()
+	if !g.headless {
+		g.LoadSound()
+	}
+	g.CreateControlsMenu()
+	g.CreateLobbyMenu()
+	g.LayoutMenus()
+	g.GoToMenu(true)
+}
+
+// SimulateTick advances the ocean by one tick; online play runs it only once every player's input is in.
+func (g *Game) SimulateTick() {
+	for i, _ := range g.fish {
+		g.fish[i].Move()
+	}
+	for i := range g.players {
+		g.players[i].Move()
+	}
+	g.CollidePlayers()
+	g.CameraTick()
+	g.PopupsTick()
+	g.Notify(GameEvent{Kind: eventTick})
+}
+
 func (g *Game) Start() {
+	g.GeneratePlayers()
 	g.GenerateFish()
 	g.Restart()
 	g.paused = false
 }
 
 func (g *Game) Update() error {
+	if g.metrics != nil {
+		defer g.ObserveUpdate(time.Now())
+	}
+	g.UpdateGamepads()
+	g.achievements.ToastTick()
+	if g.net != nil {
+		g.PollNetwork()
+	}
+	if g.controlCalls != nil {
+		g.HandleControlCalls()
+	}
+	if g.spectators != nil {
+		g.PublishSnapshot()
+	}
+	if g.sound != nil {
+		g.sound.MenuTick(g)
+		g.sound.MusicTick(g)
+	}
+	if !g.paused || g.gameState != gameRunning {
+		g.ParticlesTick()
+	}
 	switch g.gameState {
 	case gameRunning:
 		return g.GameCycle()
@@ -1155,37 +1428,76 @@ func (g *Game) Update() error {
 		return g.VictoryCycle()
 	case gameOptionsMenu:
 		return g.OptionsCycle()
+	case gameControlsMenu:
+		return g.ControlsCycle()
+	case gameLobby:
+		return g.LobbyCycle()
+	case gameAchievements:
+		return g.AchievementsCycle()
+	case gameStatistics:
+		return g.StatisticsCycle()
 	}
 	return nil
 }
 
-func (g *Game) UpdateScore(targetSize float64) {
+func (g *Game) UpdateScore(eater *PlayerFish, target *Fish, bonuses []ScoreBonus) {
+	eater.Combo++
+	eater.ComboTimer = comboWindow
+	factor := float64(1)
+	for _, bonus := range bonuses {
+		factor += bonus.Factor
+	}
+	points := (g.fishSpeedModifier*target.Size*10 + g.fishPerPlane) * factor * eater.ComboMultiplier()
+	eater.Eaten++
+	eater.Score += points
 	g.eaten++
-	g.score += g.fishSpeedModifier*targetSize*10 + g.fishPerPlane
-	g.highScore = math.Max(g.highScore, g.score)
-	g.mostEaten = math.Max(g.eaten, g.mostEaten)
+	g.score += points
+	if g.TracksProgress(nil) {
+		g.highScore = math.Max(g.highScore, g.score)
+		g.mostEaten = math.Max(g.eaten, g.mostEaten)
+	}
+	if g.TracksProgress(eater) {
+		g.bestCombo = math.Max(g.bestCombo, eater.Combo)
+	}
+	g.AddPopup(target.X, target.Y, g.ScorePopupLines(eater, points, bonuses)...)
 
 }
 
-func (g *Game) VibrateGamepad(milliseconds time.Duration, strong, weak float64) {
+func (g *Game) VibrateGamepad(device InputDevice, milliseconds time.Duration, strong, weak float64) {
+	if !device.HasGamepad {
+		return
+	}
 	op := &ebiten.VibrateGamepadOptions{
 		Duration:        milliseconds * time.Millisecond,
 		StrongMagnitude: strong,
 		WeakMagnitude:   weak,
 	}
-	ebiten.VibrateGamepad(g.gamepadId, op)
+	ebiten.VibrateGamepad(device.Gamepad, op)
 }
 
-func (g *Game) VibrateGamepadQuick() {
-	g.VibrateGamepad(200, 0, 0.5)
+func (g *Game) VibrateGamepadQuick(device InputDevice) {
+	g.VibrateGamepad(device, 200, 0, 0.5)
 }
 
-func (g *Game) VibrateGamepadHeavy() {
-	g.VibrateGamepad(500, 0.5, 0)
+func (g *Game) VibrateGamepadHeavy(device InputDevice) {
+	g.VibrateGamepad(device, 500, 0.5, 0)
 }
 
 func (g *Game) VictoryCycle() error {
-	if isAnyOfKeysPressed(true, ebiten.KeySpace, ebiten.KeyEscape, ebiten.KeyEnter) || g.isAnyGamepadButtonsPressed(true, ebiten.StandardGamepadButtonRightRight, ebiten.StandardGamepadButtonRightBottom, ebiten.StandardGamepadButtonCenterRight) {
+	if g.demo {
+		g.StopDemo()
+		return nil
+	}
+	for i, _ := range g.fish {
+		g.fish[i].PlaneShiftTick()
+	}
+	for i := range g.players {
+		g.players[i].PlaneShiftTick()
+	}
+	if g.net != nil {
+		return g.NetRoundOverCycle()
+	}
+	if g.IsActionPressed(actionConfirm, true) || g.IsActionPressed(actionBack, true) {
 		g.GoToMenu(false)
 	}
 	return nil
@@ -1197,19 +1509,113 @@ func (g *Game) Win() {
 			g.fish[i].SwitchPlane()
 		}
 	}
-	if g.playerFish.Plane == 0 {
-		g.playerFish.SwitchPlane()
+	for i := range g.players {
+		if g.players[i].Plane == 0 {
+			g.players[i].SwitchPlane()
+		}
 	}
 	g.End(gameVictory)
+	g.Notify(GameEvent{Kind: eventVictory})
 }
 
 func main() {
+	bench := flag.Int("bench", 0, "play this many headless autopilot runs and print balance statistics")
+	benchTicks := flag.Int("bench-ticks", 10*60*60, "maximum ticks per benchmark run")
+	planes := flag.Float64("planes", 0, "game planes for the benchmark")
+	fishAmount := flag.Float64("fish-amount", 0, "fish per plane for the benchmark")
+	fishSpeed := flag.Float64("fish-speed", 0, "fish speed modifier for the benchmark")
+	fishSize := flag.Float64("fish-size", 0, "fish max size for the benchmark")
+	oceanSize := flag.Float64("ocean-size", 0, "ocean size in screens for the benchmark")
+	env := flag.String("env", "", `serve the learning environment on "stdio" or a TCP address such as 127.0.0.1:5555`)
+	host := flag.String("host", "", "host an online game on this address, e.g. :7777")
+	join := flag.String("join", "", "join the online game at this address, e.g. 127.0.0.1:7777")
+	netDelay := flag.Int("net-delay", 0, "online input delay in ticks, 0 picks it from the measured ping")
+	netAutopilot := flag.Bool("net-autopilot", false, "let the autopilot play your fish online, handy for soak tests")
+	spectate := flag.String("spectate", "", "stream the live game to browsers and overlays on this address, e.g. 127.0.0.1:8080")
+	control := flag.String("control", "", "accept JSON-RPC control requests on this address, e.g. 127.0.0.1:8081")
+	metrics := flag.String("metrics", "", "serve Prometheus metrics on this address, e.g. 127.0.0.1:9100")
+	watch := flag.String("watch", "", "print the spectator stream at this address as JSON lines instead of playing")
+	windowed := flag.Bool("windowed", false, "start in a half-size window, e.g. to run two copies on one machine")
+	mods := flag.String("mods", "", "load asset packs from this directory instead of mods in the config directory")
+	lang := flag.String("lang", "", fmt.Sprintf("language of the game, one of %v; the system language by default", languageCodes))
+	flag.Parse()
+	if *env != "" {
+		if err := ServeEnvironment(*env); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *watch != "" {
+		if err := WatchSpectators(*watch, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *bench > 0 {
+		options := map[int]float64{}
+		for option, value := range map[int]float64{optionPlanes: *planes, optionFishAmount: *fishAmount, optionFishSpeed: *fishSpeed, optionFishSize: *fishSize, optionOceanSize: *oceanSize} {
+			if value != 0 {
+				options[option] = value
+			}
+		}
+		result, err := RunBenchmark(*bench, *benchTicks, options)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(result)
+		return
+	}
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle(title)
-	ebiten.SetFullscreen(true)
-	if err := ebiten.RunGame(NewGame()); err != nil {
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetFullscreen(!*windowed)
+	modsPath = *mods
+	g := NewGame()
+	g.netDelay, g.netAutopilot = *netDelay, *netAutopilot
+	if *windowed {
+		ebiten.SetWindowSize(screenWidth/2, screenHeight/2)
+		g.SetOption(optionFullscreen, 0)
+	}
+	if *lang != "" {
+		index, err := languageIndex(*lang)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.SetOption(optionLanguage, index)
+		g.ApplyLanguage()
+	}
+	if *spectate != "" {
+		if err := g.ServeSpectators(*spectate); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *control != "" {
+		if err := g.ServeControl(*control); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *metrics != "" {
+		if err := g.ServeMetrics(*metrics); err != nil {
+			log.Fatal(err)
+		}
+	}
+	switch {
+	case *host != "":
+		g.GoToLobby()
+		if err := g.HostSession(*host); err != nil {
+			log.Fatal(err)
+		}
+	case *join != "":
+		g.GoToLobby()
+		g.lobbyAddress = *join
+		if err := g.JoinSession(*join); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
+	g.LeaveRun()
 }
 
 func getColorComponentByDepth(y, maxDepth, maxColorValue float64) float64 {
@@ -1257,23 +1663,13 @@ func isAnyOfKeysPressed(just bool, keys ...ebiten.Key) bool {
 
 func NewGame() *Game {
 	g := &Game{}
-	g.SetDefaultOptions()
-	g.screenWidth, g.screenHeight = screenWidth, screenHeight
-	g.fontSizes = make(map[string]float64)
-	g.SetFontsSizes()
-	g.preloadedImages = map[string]image.Image{
-		"player":   preloadImage(playerImage),
-		"bass":     preloadImage(bassImage),
-		"shark":    preloadImage(sharkImage),
-		"puffer":   preloadImage(pufferImage),
-		"goldfish": preloadImage(goldfishImage),
-		"jelly":    preloadImage(jellyImage),
-	}
-	g.GetBackgroundColor(g.screenHeight / 2)
-	g.plainFontSource = loadFont(fixedsys)
-	g.fancyFontSource = loadFont(aquawow)
-	g.playerFish.Init(g)
-	g.CreateMenus()
-	g.GoToMenu(true)
+	g.Setup()
+	return g
+}
+
+// NewHeadlessGame runs the same simulation without creating any GPU images, so it needs no window.
+func NewHeadlessGame() *Game {
+	g := &Game{headless: true}
+	g.Setup()
 	return g
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Metrics is filled in by the game loop and read by the scraper; everything goes through the mutex.
+type Metrics struct {
+	mutex        sync.Mutex
+	started      time.Time
+	lastUpdate   time.Time
+	tps, fps     float64
+	updates      float64
+	updateTime   float64
+	updateMax    float64
+	draws        float64
+	drawTime     float64
+	drawMax      float64
+	state        string
+	stateSeconds map[string]float64
+	alive        map[[2]string]float64
+	playerSizes  []float64
+	score, eaten float64
+	deaths       map[string]float64
+}
+
+// ServeMetrics exposes the Prometheus text format on /metrics.
+func (g *Game) ServeMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	m := &Metrics{started: time.Now(), stateSeconds: map[string]float64{}, alive: map[[2]string]float64{}, deaths: map[string]float64{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.Write(w)
+	})
+	g.metrics = m
+	log.Printf("metrics on http://%s/metrics", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("metrics server: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (m *Metrics) Handle(event GameEvent) {
+	if event.Kind != eventDeath {
+		return
+	}
+	m.mutex.Lock()
+	m.deaths[event.Fish.Type]++
+	m.mutex.Unlock()
+}
+
+// ObserveUpdate is deferred from Update with the time it started; it also samples the ocean.
+func (g *Game) ObserveUpdate(started time.Time) {
+	m := g.metrics
+	now := time.Now()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	elapsed := now.Sub(started).Seconds()
+	m.updates++
+	m.updateTime += elapsed
+	m.updateMax = max(m.updateMax, elapsed)
+	if !m.lastUpdate.IsZero() {
+		m.stateSeconds[m.state] += now.Sub(m.lastUpdate).Seconds()
+	}
+	m.lastUpdate = now
+	m.state = gameStateNames[g.gameState]
+	m.tps, m.fps = ebiten.ActualTPS(), ebiten.ActualFPS()
+	clear(m.alive)
+	for i := range g.fish {
+		if !g.fish[i].Dead {
+			m.alive[[2]string{g.fish[i].Type, fmt.Sprint(g.fish[i].Plane)}]++
+		}
+	}
+	m.playerSizes = m.playerSizes[:0]
+	for i := range g.players {
+		m.playerSizes = append(m.playerSizes, g.players[i].Size)
+	}
+	m.score, m.eaten = g.score, g.eaten
+}
+
+func (g *Game) ObserveDraw(started time.Time) {
+	m := g.metrics
+	elapsed := time.Since(started).Seconds()
+	m.mutex.Lock()
+	m.draws++
+	m.drawTime += elapsed
+	m.drawMax = max(m.drawMax, elapsed)
+	m.mutex.Unlock()
+}
+
+// Write renders every metric; the _max gauges cover the time since the previous scrape, so stalls between scrapes still show up.
+func (m *Metrics) Write(w io.Writer) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	sample := func(name string, value float64, labels ...string) {
+		pairs := []string{}
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+		}
+		if len(pairs) > 0 {
+			name += "{" + strings.Join(pairs, ",") + "}"
+		}
+		fmt.Fprintf(w, "%s %g\n", name, value)
+	}
+	sortedKeys := func(values map[string]float64) []string {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return keys
+	}
+
+	metric("fish30d_uptime_seconds", "gauge", "Seconds since the metrics endpoint started.")
+	sample("fish30d_uptime_seconds", time.Since(m.started).Seconds())
+	metric("fish30d_ticks_per_second", "gauge", "Actual game updates per second.")
+	sample("fish30d_ticks_per_second", m.tps)
+	metric("fish30d_frames_per_second", "gauge", "Actual frames drawn per second.")
+	sample("fish30d_frames_per_second", m.fps)
+
+	metric("fish30d_updates_total", "counter", "Game updates run.")
+	sample("fish30d_updates_total", m.updates)
+	metric("fish30d_update_seconds_total", "counter", "Time spent in game updates.")
+	sample("fish30d_update_seconds_total", m.updateTime)
+	metric("fish30d_update_seconds_max", "gauge", "Slowest game update since the last scrape.")
+	sample("fish30d_update_seconds_max", m.updateMax)
+	metric("fish30d_draws_total", "counter", "Frames drawn.")
+	sample("fish30d_draws_total", m.draws)
+	metric("fish30d_draw_seconds_total", "counter", "Time spent drawing.")
+	sample("fish30d_draw_seconds_total", m.drawTime)
+	metric("fish30d_draw_seconds_max", "gauge", "Slowest frame since the last scrape.")
+	sample("fish30d_draw_seconds_max", m.drawMax)
+	m.updateMax, m.drawMax = 0, 0
+
+	metric("fish30d_fish_alive", "gauge", "Living NPC fish by species and plane.")
+	alive := make([][2]string, 0, len(m.alive))
+	for key := range m.alive {
+		alive = append(alive, key)
+	}
+	slices.SortFunc(alive, func(a, b [2]string) int { return strings.Compare(a[0]+"/"+a[1], b[0]+"/"+b[1]) })
+	for _, key := range alive {
+		sample("fish30d_fish_alive", m.alive[key], "species", key[0], "plane", key[1])
+	}
+	metric("fish30d_player_size", "gauge", "Size of each player fish.")
+	for i, size := range m.playerSizes {
+		sample("fish30d_player_size", size, "player", fmt.Sprint(i+1))
+	}
+	metric("fish30d_score", "gauge", "Score of the current game.")
+	sample("fish30d_score", m.score)
+	metric("fish30d_eaten", "gauge", "Fish eaten in the current game.")
+	sample("fish30d_eaten", m.eaten)
+	metric("fish30d_deaths_total", "counter", "Player deaths by the species that ate them.")
+	for _, killer := range sortedKeys(m.deaths) {
+		sample("fish30d_deaths_total", m.deaths[killer], "killer", killer)
+	}
+
+	metric("fish30d_game_state", "gauge", "1 for the screen the game is on.")
+	if m.state != "" {
+		sample("fish30d_game_state", 1, "state", m.state)
+	}
+	metric("fish30d_state_seconds_total", "counter", "Time spent on each screen.")
+	for _, state := range sortedKeys(m.stateSeconds) {
+		sample("fish30d_state_seconds_total", m.stateSeconds[state], "state", state)
+	}
+
+	metric("go_goroutines", "gauge", "Goroutines currently running.")
+	sample("go_goroutines", float64(runtime.NumGoroutine()))
+	metric("go_memstats_heap_alloc_bytes", "gauge", "Heap bytes allocated and still in use.")
+	sample("go_memstats_heap_alloc_bytes", float64(memory.HeapAlloc))
+	metric("go_memstats_heap_objects", "gauge", "Objects allocated on the heap.")
+	sample("go_memstats_heap_objects", float64(memory.HeapObjects))
+}
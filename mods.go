@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	modsDirName     = "mods"
+	modManifestName = "mod.json"
+	modErrorLines   = 6
+)
+
+// modsPath overrides the mods directory in the config directory; -mods sets it.
+var modsPath string
+
+// ModPack is an asset pack: a directory with a mod.json manifest. Paths in the manifest are relative to the directory.
+// Packs load in order of priority, so a pack with a higher priority wins wherever two packs replace the same thing;
+// the embedded assets are the base pack under all of them.
+type ModPack struct {
+	Name     string            `json:"name"`
+	Version  string            `json:"version"`
+	Priority int               `json:"priority"`
+	Sprites  map[string]string `json:"sprites"`
+	Fonts    map[string]string `json:"fonts"`
+	Tips     []Tip             `json:"tips"`
+	Species  []Species         `json:"species"`
+	Synth    string            `json:"synth"`
+	Sounds   map[string]string `json:"sounds"`
+	dir      string
+}
+
+func (pack *ModPack) path(name string) string {
+	return filepath.Join(pack.dir, filepath.FromSlash(name))
+}
+
+// ModError records a problem with a pack; the broken part is skipped and the rest of the pack still loads.
+func (g *Game) ModError(pack *ModPack, format string, args ...any) {
+	message := pack.Name + ": " + fmt.Sprintf(format, args...)
+	log.Printf("mods: %s", message)
+	g.modErrors = append(g.modErrors, message)
+}
+
+// LoadMods reads every manifest in the mods directory. Headless games stay with the base pack,
+// so benchmarks and training runs are comparable wherever they run.
+func (g *Game) LoadMods() {
+	if g.headless {
+		return
+	}
+	dir := modsPath
+	if dir == "" {
+		var err error
+		if dir, err = configFilePath(modsDirName); err != nil {
+			log.Printf("mods: %v", err)
+			return
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		g.modErrors = append(g.modErrors, err.Error())
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pack := &ModPack{Name: entry.Name(), dir: filepath.Join(dir, entry.Name())}
+		data, err := os.ReadFile(pack.path(modManifestName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			err = json.Unmarshal(data, pack)
+		}
+		if err != nil {
+			g.ModError(pack, "%s: %v", modManifestName, err)
+			continue
+		}
+		if pack.Name == "" {
+			pack.Name = entry.Name()
+		}
+		g.mods = append(g.mods, pack)
+	}
+	slices.SortStableFunc(g.mods, func(a, b *ModPack) int {
+		if a.Priority != b.Priority {
+			return a.Priority - b.Priority
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// ModsFingerprint names the loaded packs; online peers have to agree on it, since sprites and species change the simulation.
+func (g *Game) ModsFingerprint() string {
+	var packs []string
+	for _, pack := range g.mods {
+		packs = append(packs, pack.Name+"@"+pack.Version)
+	}
+	return strings.Join(packs, ", ")
+}
+
+// ApplyModSprites replaces or adds sprites; it runs before the growth stages are recolored from the player sprite.
+func (g *Game) ApplyModSprites() {
+	for _, pack := range g.mods {
+		for _, name := range sortedKeys(pack.Sprites) {
+			img, err := loadModImage(pack.path(pack.Sprites[name]))
+			if err != nil {
+				g.ModError(pack, "sprite %q: %v", name, err)
+				continue
+			}
+			g.preloadedImages[name] = img
+		}
+	}
+}
+
+// sortedKeys lists the names a pack maps to files in a fixed order, so its problems are always reported alike.
+func sortedKeys(files map[string]string) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func loadModImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// ModFontSources puts the fonts of the packs in front of the base ones, the highest priority first.
+// The base fonts stay behind them as fallbacks for glyphs a pack font lacks.
+func (g *Game) ModFontSources(kind string, base []*text.GoTextFaceSource) []*text.GoTextFaceSource {
+	for _, pack := range g.mods {
+		path, ok := pack.Fonts[kind]
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(pack.path(path))
+		var source *text.GoTextFaceSource
+		if err == nil {
+			source, err = text.NewGoTextFaceSource(bytes.NewReader(data))
+		}
+		if err != nil {
+			g.ModError(pack, "%s font: %v", kind, err)
+			continue
+		}
+		base = append([]*text.GoTextFaceSource{source}, base...)
+	}
+	return base
+}
+
+// ValidateModFonts reports font kinds nobody asks for, most likely a typo.
+func (g *Game) ValidateModFonts() {
+	for _, pack := range g.mods {
+		for kind := range pack.Fonts {
+			if kind != "fancy" && kind != "plain" {
+				g.ModError(pack, "unknown font %q, expected fancy or plain", kind)
+			}
+		}
+	}
+}
+
+// ApplyModTips adds the tips of the packs; a tip with the ID of an existing one replaces it.
+func (g *Game) ApplyModTips() {
+	for _, pack := range g.mods {
+	next:
+		for _, tip := range pack.Tips {
+			if tip.ID == "" || tip.Text == "" {
+				g.ModError(pack, "tip %q needs an id and a text", tip.ID)
+				continue
+			}
+			for _, tag := range tip.Tags {
+				species, killer := strings.CutPrefix(tag, "killer:")
+				if _, ok := tipConditions[tag]; !ok && !(killer && (species == "player" || slices.Contains(behaviours, species))) {
+					g.ModError(pack, "tip %q: unknown tag %q", tip.ID, tag)
+					continue next
+				}
+			}
+			if i := slices.IndexFunc(tips, func(known Tip) bool { return known.ID == tip.ID }); i >= 0 {
+				tips[i] = tip
+			} else {
+				tips = append(tips, tip)
+			}
+		}
+	}
+}
+
+// ApplyModSpecies lets the pack with the highest priority that defines species replace the whole table,
+// since the shares only make sense together. A table that doesn't check out is reported and ignored.
+func (g *Game) ApplyModSpecies() {
+	g.species = slices.Clone(baseSpecies)
+	for i := len(g.mods) - 1; i >= 0; i-- {
+		pack := g.mods[i]
+		if len(pack.Species) == 0 {
+			continue
+		}
+		if err := g.ValidateSpecies(pack.Species); err != nil {
+			g.ModError(pack, "species: %v", err)
+			continue
+		}
+		g.species = slices.Clone(pack.Species)
+		return
+	}
+}
+
+func (g *Game) ValidateSpecies(table []Species) error {
+	total, shared := 0.0, false
+	for _, species := range table {
+		switch {
+		case species.Name == "":
+			return errors.New("every species needs a name")
+		case !slices.Contains(behaviours, species.Behaviour):
+			return fmt.Errorf("%s: unknown behaviour %q, expected one of %v", species.Name, species.Behaviour, behaviours)
+		case g.preloadedImages[species.Sprite] == nil:
+			return fmt.Errorf("%s: unknown sprite %q", species.Name, species.Sprite)
+		case species.Share < 0:
+			return fmt.Errorf("%s: share can't be negative", species.Name)
+		}
+		total += species.Share
+		shared = shared || species.Share > 0
+	}
+	if !shared || math.Abs(total-1) > 0.01 {
+		return fmt.Errorf("shares add up to %v instead of 1", total)
+	}
+	return nil
+}
+
+// ApplyModSynth retunes or adds synth presets from the synth.json of the packs.
+func (g *Game) ApplyModSynth(presets map[string]SynthPreset) {
+	for _, pack := range g.mods {
+		if pack.Synth == "" {
+			continue
+		}
+		data, err := os.ReadFile(pack.path(pack.Synth))
+		if err == nil {
+			err = json.Unmarshal(data, &presets)
+		}
+		if err != nil {
+			g.ModError(pack, "synth: %v", err)
+		}
+	}
+}
+
+// ApplyModSounds replaces rendered clips with WAV files; each has to name a sound the game plays.
+func (g *Game) ApplyModSounds(s *Sound) {
+	for _, pack := range g.mods {
+		for _, name := range sortedKeys(pack.Sounds) {
+			if _, ok := s.clips[name]; !ok {
+				g.ModError(pack, "unknown sound %q", name)
+				continue
+			}
+			clip, err := loadModSound(pack.path(pack.Sounds[name]))
+			if err != nil {
+				g.ModError(pack, "sound %q: %v", name, err)
+				continue
+			}
+			s.clips[name], s.muffled[name] = clip, muffle(clip)
+		}
+	}
+}
+
+func loadModSound(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stream, err := wav.DecodeWithSampleRate(audioSampleRate, file)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(stream)
+}
+
+// DrawModErrors lists what went wrong with the packs next to the main menu, so a modder needn't dig through logs.
+func (g *Game) DrawModErrors() {
+	if len(g.modErrors) == 0 {
+		return
+	}
+	messages := g.modErrors
+	if len(messages) > modErrorLines {
+		more := len(messages) - modErrorLines + 1
+		messages = append(slices.Clone(messages[:modErrorLines-1]), g.trn("and %d more problems", more, more))
+	}
+	face := g.GetFontFace("small", false)
+	lines := []string{g.tr("Mod problems:")}
+	for _, message := range messages {
+		lines = append(lines, wrapText(message, face, 0.4*g.screenWidth)...)
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.56*g.screenWidth, 0.9*g.screenHeight-float64(len(lines))*1.2*g.Font("small"))
+	op.ColorScale.ScaleWithColor(color.RGBA{255, 110, 90, 255})
+	for _, line := range lines {
+		text.Draw(g.screen, line, face, op)
+		op.GeoM.Translate(0, 1.2*g.Font("small"))
+	}
+}
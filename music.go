@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+const (
+	stemBase = iota
+	stemShallow
+	stemDeep
+	stemDanger
+	stemCount
+)
+
+const (
+	crossfadeRate = 0.02
+	dangerFrames  = 4 * 60
+	spreeLength   = 5
+)
+
+// stemNames are the synth presets of the stems; they all loop over the same number of bars, so they stay in step.
+var stemNames = [stemCount]string{"stem-base", "stem-shallow", "stem-deep", "stem-danger"}
+
+// Music plays every stem at once from the start and only ever crossfades their volumes.
+type Music struct {
+	stems  [stemCount]*audio.Player
+	levels [stemCount]float64
+	danger float64
+}
+
+func (s *Sound) StartMusic() {
+	for i, name := range stemNames {
+		stem := s.clips[name]
+		player, err := s.context.NewPlayer(audio.NewInfiniteLoop(bytes.NewReader(stem), int64(len(stem))))
+		if err != nil {
+			log.Fatal(err)
+		}
+		player.SetVolume(0)
+		player.Play()
+		s.music.stems[i] = player
+	}
+}
+
+// MusicTargets maps the game to stem levels: brighter near the surface, darker at depth, and the danger layer after a shark lunge.
+func (s *Sound) MusicTargets(g *Game) (targets [stemCount]float64) {
+	targets[stemBase], targets[stemShallow] = 1, 1
+	if g.gameState != gameRunning || len(g.players) == 0 {
+		return
+	}
+	depth := math.Max(0, math.Min(1, g.LeadPlayer().Y/g.worldHeight))
+	targets[stemShallow], targets[stemDeep] = 1-depth, depth
+	targets[stemDanger] = s.music.danger / dangerFrames
+	return
+}
+
+// MusicTick moves every stem a little towards its target each frame, which makes the crossfades last about a second.
+func (s *Sound) MusicTick(g *Game) {
+	m := &s.music
+	m.danger = math.Max(0, m.danger-1)
+	targets := s.MusicTargets(g)
+	for i, player := range m.stems {
+		m.levels[i] += crossfadeRate * (targets[i] - m.levels[i])
+		volume := musicVolume * s.volume * m.levels[i]
+		if s.muted {
+			volume = 0
+		}
+		player.SetVolume(volume)
+	}
+}
+
+// HandleMusic reacts to the events the music follows; a lunge keeps the danger layer up for a few seconds.
+func (s *Sound) HandleMusic(event GameEvent) {
+	switch event.Kind {
+	case eventLunge:
+		s.music.danger = dangerFrames
+	case eventEat:
+		if combo := int(event.Player.Combo); combo >= spreeLength && combo%spreeLength == 0 {
+			s.Play("stinger", 0, 1)
+		}
+	case eventDeath, eventGameOver, eventVictory:
+		s.music.danger = 0
+	}
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+)
+
+const (
+	netDefaultAddress = "127.0.0.1:7777"
+	netHashInterval   = 60
+	netPingInterval   = 60
+	netMinDelay       = 2
+	netMaxDelay       = 15
+	netStallNotice    = 30
+	netWriteTimeout   = 5 * time.Second
+)
+
+// netSyncedOptions are the options the host dictates; fullscreen and the like stay local.
+var netSyncedOptions = []int{optionPlanes, optionFishAmount, optionFishSpeed, optionFishSize, optionFishReactions, optionOceanSize, optionPlayerCollisions}
+
+// NetMessage is one JSON line on the wire; Type decides which fields are set.
+type NetMessage struct {
+	Type    string          `json:"type"`
+	Round   int             `json:"round,omitempty"`
+	Tick    int             `json:"tick,omitempty"`
+	Player  int             `json:"player,omitempty"`
+	Players int             `json:"players,omitempty"`
+	Frame   InputFrame      `json:"frame"`
+	Frames  []InputFrame    `json:"frames,omitempty"`
+	Hash    uint64          `json:"hash,omitempty"`
+	Seed    int64           `json:"seed,omitempty"`
+	Delay   int             `json:"delay,omitempty"`
+	Options map[int]float64 `json:"options,omitempty"`
+	Time    int64           `json:"time,omitempty"`
+	Text    string          `json:"text,omitempty"`
+}
+
+type netPeer struct {
+	conn    net.Conn
+	encoder *json.Encoder
+	player  int
+	joined  bool
+	dropped bool
+	rtt     time.Duration
+}
+
+type netEvent struct {
+	peer    *netPeer
+	message NetMessage
+	err     error
+}
+
+type netTick struct {
+	frames []InputFrame
+	have   []bool
+}
+
+// NetSession runs deterministic lockstep: every peer simulates tick t only once it holds every player's input for t.
+// Clients send their input to the host, which relays the complete set of inputs for each tick back to everyone.
+// Inputs are scheduled delay ticks ahead, so the round trip to the host is hidden as long as it stays under the delay.
+type NetSession struct {
+	host         bool
+	listener     net.Listener
+	peers        []*netPeer
+	events       chan netEvent
+	done         chan struct{}
+	local        int
+	playerCount  int
+	fixedDelay   int
+	delay        int
+	running      bool
+	round        int
+	tick         int
+	collected    int
+	frames       map[int][]InputFrame
+	pending      map[int]*netTick
+	current      []InputFrame
+	hashes       map[int]uint64
+	peerHashes   map[int][]peerHash
+	localInput   InputSource
+	polled       bool
+	stalledTicks int
+	clock        int
+	rtt          time.Duration
+	preset       map[int]float64
+	savedOptions []int
+	status       string
+}
+
+// peerHash is a client hash for a tick the host hasn't simulated yet; it is checked once the host gets there.
+type peerHash struct {
+	peer *netPeer
+	hash uint64
+}
+
+// LockstepInput replays the inputs the session agreed on for the tick being simulated.
+type LockstepInput struct {
+	session *NetSession
+	index   int
+}
+
+func (input *LockstepInput) Poll(fish *PlayerFish) InputFrame {
+	if input.index >= len(input.session.current) {
+		return InputFrame{}
+	}
+	return input.session.current[input.index]
+}
+
+func newNetSession(g *Game, host bool) *NetSession {
+	s := &NetSession{
+		host:       host,
+		events:     make(chan netEvent, 256),
+		done:       make(chan struct{}),
+		fixedDelay: g.netDelay,
+		localInput: &DeviceInput{},
+		hashes:     map[int]uint64{},
+		peerHashes: map[int][]peerHash{},
+	}
+	if g.netAutopilot {
+		s.localInput = &AgentInput{Policy: &Autopilot{}}
+	}
+	for _, item := range g.optionsMenu {
+		s.savedOptions = append(s.savedOptions, item.selector)
+	}
+	return s
+}
+
+// HostSession listens on the port of addr on every interface, so LAN players can join as well as local ones.
+func (g *Game) HostSession(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	s := newNetSession(g, true)
+	s.listener, s.playerCount = listener, 1
+	s.status = g.tr("Hosting on port %s", port)
+	g.net = s
+	go s.accept()
+	return nil
+}
+
+func (g *Game) JoinSession(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	s := newNetSession(g, false)
+	peer := s.addPeer(conn)
+	s.status = g.tr("Connecting to %s", addr)
+	g.net = s
+	return s.send(peer, NetMessage{Type: "hello", Text: g.ModsFingerprint()})
+}
+
+// LeaveSession closes every connection, hands the player their own options back and returns to the lobby.
+func (g *Game) LeaveSession(notice string) {
+	s := g.net
+	if s == nil {
+		return
+	}
+	s.broadcast(NetMessage{Type: "bye"})
+	s.close()
+	g.net = nil
+	for i, selector := range s.savedOptions {
+		g.optionsMenu[i].selector = selector
+	}
+	g.ApplyOptions()
+	for i := range g.players {
+		g.players[i].Input = &DeviceInput{}
+	}
+	if notice != "" {
+		g.ShowNotice(notice)
+	}
+	g.GoToLobby()
+}
+
+// PollNetwork handles everything the peers sent since the last update.
+func (g *Game) PollNetwork() {
+	s := g.net
+	s.polled = false
+	s.clock++
+	if s.host && g.gameState != gameRunning && s.clock%netPingInterval == 0 {
+		s.broadcast(NetMessage{Type: "ping", Time: time.Now().UnixNano()})
+	}
+	for {
+		select {
+		case event := <-s.events:
+			if event.err != nil {
+				g.DropPeer(event.peer)
+			} else if s.host {
+				g.HandleClientMessage(event.peer, event.message)
+			} else {
+				g.HandleHostMessage(event.message)
+			}
+			if g.net != s {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (g *Game) DropPeer(peer *netPeer) {
+	s := g.net
+	if !s.host {
+		g.LeaveSession(g.tr("Lost connection to the host"))
+		return
+	}
+	if peer.dropped {
+		return
+	}
+	peer.dropped = true
+	peer.conn.Close()
+	if !peer.joined {
+		return
+	}
+	if s.running {
+		g.ShowNotice(g.tr("P%d left the game", peer.player+1))
+		s.collect()
+		return
+	}
+	s.renumber()
+	s.broadcastLobby()
+}
+
+func (g *Game) HandleClientMessage(peer *netPeer, message NetMessage) {
+	s := g.net
+	if (message.Type == "input" || message.Type == "hash") && message.Round != s.round {
+		return
+	}
+	switch message.Type {
+	case "connect":
+		s.peers = append(s.peers, peer)
+	case "hello":
+		switch {
+		case s.running || g.gameState != gameLobby:
+			s.send(peer, NetMessage{Type: "reject", Text: "The game has already started"})
+			g.DropPeer(peer)
+		case s.playerCount >= maxPlayers:
+			s.send(peer, NetMessage{Type: "reject", Text: "The game is full"})
+			g.DropPeer(peer)
+		case message.Text != g.ModsFingerprint():
+			s.send(peer, NetMessage{Type: "reject", Text: "Your asset packs differ from the host's"})
+			g.DropPeer(peer)
+		default:
+			peer.joined = true
+			s.renumber()
+			s.broadcastLobby()
+		}
+	case "input":
+		tick := s.pendingTick(message.Tick)
+		if tick == nil {
+			return
+		}
+		tick.frames[peer.player], tick.have[peer.player] = message.Frame, true
+		s.collect()
+	case "hash":
+		if _, ok := s.hashes[message.Tick]; !ok {
+			s.peerHashes[message.Tick] = append(s.peerHashes[message.Tick], peerHash{peer, message.Hash})
+			return
+		}
+		g.CheckHash(peer, message.Tick, message.Hash)
+	case "ping":
+		s.send(peer, NetMessage{Type: "pong", Time: message.Time})
+	case "pong":
+		peer.rtt = time.Duration(time.Now().UnixNano() - message.Time)
+	case "bye":
+		g.DropPeer(peer)
+	}
+}
+
+func (g *Game) HandleHostMessage(message NetMessage) {
+	s := g.net
+	switch message.Type {
+	case "lobby":
+		s.local, s.playerCount = message.Player, message.Players
+		s.status = g.trn("Joined as P%d, %d players in the lobby. Waiting for the host to start", s.playerCount, s.local+1, s.playerCount)
+	case "start":
+		g.BeginRound(message)
+	case "tick":
+		s.frames[message.Tick] = message.Frames
+	case "ping":
+		s.send(s.peers[0], NetMessage{Type: "pong", Time: message.Time})
+		s.send(s.peers[0], NetMessage{Type: "ping", Time: time.Now().UnixNano()})
+	case "pong":
+		s.rtt = time.Duration(time.Now().UnixNano() - message.Time)
+	case "desync":
+		g.LeaveSession(g.tr("Desync detected at tick %d", message.Tick))
+	case "reject":
+		g.LeaveSession(message.Text)
+	case "bye":
+		g.LeaveSession(g.tr("The host closed the game"))
+	}
+}
+
+// StartRound picks a fresh seed and tells every peer to begin the same round; only the host calls it.
+func (g *Game) StartRound() {
+	s := g.net
+	options := map[int]float64{}
+	for _, option := range netSyncedOptions {
+		options[option] = g.optionsMenu[option].GetValue()
+		if value, ok := s.preset[option]; ok {
+			options[option] = value
+		}
+	}
+	s.renumber()
+	s.rtt = 0
+	for _, peer := range s.peers {
+		if peer.joined {
+			s.rtt = max(s.rtt, peer.rtt)
+		}
+	}
+	delay := s.fixedDelay
+	if delay <= 0 {
+		delay = int(math.Ceil(s.rtt.Seconds()*60)) + 1
+	}
+	message := NetMessage{Type: "start", Round: s.round + 1, Seed: time.Now().UnixNano(), Players: s.playerCount, Options: options, Delay: min(max(delay, netMinDelay), netMaxDelay)}
+	for _, peer := range s.peers {
+		if peer.joined {
+			message.Player = peer.player
+			s.send(peer, message)
+		}
+	}
+	message.Player = 0
+	g.BeginRound(message)
+}
+
+// BeginRound sets up the round identically on every peer: same options, same seed, same players.
+func (g *Game) BeginRound(message NetMessage) {
+	s := g.net
+	for option, value := range message.Options {
+		if err := g.SetOption(option, value); err != nil {
+			g.LeaveSession(err.Error())
+			return
+		}
+	}
+	if err := g.SetOption(optionPlayers, float64(message.Players)); err != nil {
+		g.LeaveSession(err.Error())
+		return
+	}
+	s.local, s.playerCount, s.delay, s.round = message.Player, message.Players, message.Delay, message.Round
+	s.running, s.tick, s.collected, s.stalledTicks = true, 0, s.delay, 0
+	s.frames, s.pending = map[int][]InputFrame{}, map[int]*netTick{}
+	clear(s.hashes)
+	clear(s.peerHashes)
+	for t := 0; t < s.delay; t++ {
+		s.frames[t] = make([]InputFrame, s.playerCount)
+	}
+	g.rng = rand.New(rand.NewSource(message.Seed))
+	g.ApplyOptions()
+	g.Start()
+	for i := range g.players {
+		g.players[i].Input = &LockstepInput{session: s, index: i}
+	}
+}
+
+// NetGameCycle advances the shared round as far as the received inputs allow, running an extra tick when a client has fallen behind.
+func (g *Game) NetGameCycle() error {
+	s := g.net
+	if g.IsActionPressed(actionBack, true) {
+		g.LeaveSession(g.tr("You left the game"))
+		return nil
+	}
+	for steps := 0; steps < 2 && g.gameState == gameRunning && (steps == 0 || len(s.frames) > s.delay) && s.BeginTick(g); steps++ {
+		g.SimulateTick()
+		s.EndTick(g)
+	}
+	if !g.AllPlayersDead() {
+		g.GetBackgroundColor(g.LeadPlayer().Y)
+	}
+	return nil
+}
+
+func (g *Game) NetRoundOverCycle() error {
+	if g.IsActionPressed(actionBack, true) {
+		g.LeaveSession("")
+		return nil
+	}
+	if g.net.host && g.IsActionPressed(actionConfirm, true) {
+		g.StartRound()
+	}
+	return nil
+}
+
+func (s *NetSession) BeginTick(g *Game) bool {
+	frames, ok := s.frames[s.tick]
+	if !ok {
+		s.stalledTicks++
+		return false
+	}
+	delete(s.frames, s.tick)
+	s.current, s.stalledTicks = frames, 0
+	frame := s.localInput.Poll(&g.players[s.local])
+	if s.polled {
+		frame.Plane, frame.Dash = false, false
+	}
+	s.polled = true
+	s.SendInput(s.tick+s.delay, frame)
+	return true
+}
+
+func (s *NetSession) EndTick(g *Game) {
+	if s.tick%netHashInterval == 0 {
+		hash := g.StateHash()
+		if s.host {
+			s.hashes[s.tick] = hash
+			delete(s.hashes, s.tick-10*netHashInterval)
+			buffered := s.peerHashes[s.tick]
+			delete(s.peerHashes, s.tick)
+			for _, client := range buffered {
+				if !g.CheckHash(client.peer, s.tick, client.hash) {
+					return
+				}
+			}
+		} else {
+			s.send(s.peers[0], NetMessage{Type: "hash", Round: s.round, Tick: s.tick, Hash: hash})
+		}
+	}
+	s.tick++
+}
+
+// CheckHash compares a client hash with the one of the host and ends the session on a mismatch.
+func (g *Game) CheckHash(peer *netPeer, tick int, hash uint64) bool {
+	s := g.net
+	if known, ok := s.hashes[tick]; !ok || known == hash {
+		return true
+	}
+	s.broadcast(NetMessage{Type: "desync", Tick: tick})
+	g.LeaveSession(g.tr("Desync with P%d at tick %d", peer.player+1, tick))
+	return false
+}
+
+func (s *NetSession) SendInput(tick int, frame InputFrame) {
+	if !s.host {
+		s.send(s.peers[0], NetMessage{Type: "input", Round: s.round, Tick: tick, Player: s.local, Frame: frame})
+		return
+	}
+	pending := s.pendingTick(tick)
+	pending.frames[0], pending.have[0] = frame, true
+	s.collect()
+}
+
+// collect relays every tick whose inputs are complete, in order; dropped players count as idle.
+func (s *NetSession) collect() {
+	for {
+		pending, ok := s.pending[s.collected]
+		if !ok || !pending.have[0] {
+			return
+		}
+		for _, peer := range s.peers {
+			if peer.joined && !peer.dropped && !pending.have[peer.player] {
+				return
+			}
+		}
+		s.frames[s.collected] = pending.frames
+		s.broadcast(NetMessage{Type: "tick", Tick: s.collected, Frames: pending.frames})
+		delete(s.pending, s.collected)
+		s.collected++
+	}
+}
+
+func (s *NetSession) pendingTick(tick int) *netTick {
+	if !s.running || tick < s.collected {
+		return nil
+	}
+	if _, ok := s.pending[tick]; !ok {
+		s.pending[tick] = &netTick{frames: make([]InputFrame, s.playerCount), have: make([]bool, s.playerCount)}
+	}
+	return s.pending[tick]
+}
+
+// renumber forgets departed peers and gives the rest consecutive player slots after the host.
+func (s *NetSession) renumber() {
+	peers := s.peers[:0]
+	for _, peer := range s.peers {
+		if !peer.dropped {
+			peers = append(peers, peer)
+		}
+	}
+	s.peers = peers
+	s.playerCount = 1
+	for _, peer := range s.peers {
+		if peer.joined {
+			peer.player = s.playerCount
+			s.playerCount++
+		}
+	}
+}
+
+func (s *NetSession) broadcastLobby() {
+	for _, peer := range s.peers {
+		if peer.joined {
+			s.send(peer, NetMessage{Type: "lobby", Player: peer.player, Players: s.playerCount})
+		}
+	}
+}
+
+func (s *NetSession) addPeer(conn net.Conn) *netPeer {
+	peer := &netPeer{conn: conn, encoder: json.NewEncoder(conn)}
+	s.peers = append(s.peers, peer)
+	go s.read(peer)
+	return peer
+}
+
+func (s *NetSession) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		peer := &netPeer{conn: conn, encoder: json.NewEncoder(conn)}
+		select {
+		case s.events <- netEvent{peer: peer, message: NetMessage{Type: "connect"}}:
+			go s.read(peer)
+		case <-s.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+func (s *NetSession) read(peer *netPeer) {
+	decoder := json.NewDecoder(peer.conn)
+	for {
+		event := netEvent{peer: peer}
+		event.err = decoder.Decode(&event.message)
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+		if event.err != nil {
+			return
+		}
+	}
+}
+
+func (s *NetSession) send(peer *netPeer, message NetMessage) error {
+	if peer.dropped {
+		return nil
+	}
+	peer.conn.SetWriteDeadline(time.Now().Add(netWriteTimeout))
+	return peer.encoder.Encode(message)
+}
+
+func (s *NetSession) broadcast(message NetMessage) {
+	for _, peer := range s.peers {
+		if peer.joined || !s.host {
+			s.send(peer, message)
+		}
+	}
+}
+
+func (s *NetSession) close() {
+	close(s.done)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for _, peer := range s.peers {
+		peer.conn.Close()
+	}
+}
+
+// StateHash fingerprints everything the simulation decides, so peers can compare notes without sending the whole ocean.
+func (g *Game) StateHash() uint64 {
+	hash := fnv.New64a()
+	write := func(values ...float64) {
+		for _, value := range values {
+			hash.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(value)))
+		}
+	}
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	for i := range g.fish {
+		fish := &g.fish[i]
+		write(fish.X, fish.Y, fish.SpeedX, fish.SpeedY, fish.Size, fish.Plane, fish.Cooldown, flag(fish.Dead))
+	}
+	for i := range g.players {
+		player := &g.players[i]
+		write(player.X, player.Y, player.SpeedX, player.SpeedY, player.Size, player.Plane, player.Score, flag(player.Dead))
+	}
+	write(g.score, g.eaten, float64(g.gameState))
+	return hash.Sum64()
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	particleBubble = iota
+	particleScale
+	particlePlankton
+	particlePuff
+)
+
+const (
+	maxParticles     = 1024
+	planktonPerPlane = 40
+	trailRate        = 0.03
+	particleFade     = 20
+)
+
+// Particle is purely visual. Particles use their own random numbers, never g.rng, so they can't knock an online game out of step.
+type Particle struct {
+	Kind           int
+	X, Y           float64
+	SpeedX, SpeedY float64
+	Size           float64
+	Angle, Spin    float64
+	Plane          float64
+	Life, MaxLife  float64
+	Color          color.NRGBA
+}
+
+// bubbleImage is a ring and dotImage a filled circle; every particle is one of them scaled and tinted, so ebiten batches them.
+var bubbleImage, dotImage *ebiten.Image
+
+// AddParticle takes a slot from the pool; when the pool is full the particle is dropped, as nobody misses one bubble among a thousand.
+func (g *Game) AddParticle(p Particle) {
+	if g.headless || len(g.particles) == maxParticles {
+		return
+	}
+	p.MaxLife = p.Life
+	g.particles = g.particleStaticArray[:len(g.particles)+1]
+	g.particles[len(g.particles)-1] = p
+}
+
+// SeedPlankton empties the pool and scatters plankton over every plane; they drift forever and never leave the pool,
+// wrapping around the view instead, so the water looks equally full wherever the camera goes.
+func (g *Game) SeedPlankton() {
+	g.particles = g.particleStaticArray[:0]
+	left, top, right, bottom := g.View()
+	for plane := 0.0; plane < g.planeCount; plane++ {
+		for i := 0; i < planktonPerPlane; i++ {
+			g.AddParticle(Particle{
+				Kind:   particlePlankton,
+				X:      left + rand.Float64()*(right-left),
+				Y:      top + rand.Float64()*(bottom-top),
+				SpeedX: 0.2 * (rand.Float64() - 0.5),
+				SpeedY: 0.2 * (rand.Float64() - 0.5),
+				Size:   1 + 2*rand.Float64(),
+				Plane:  plane,
+				Color:  color.NRGBA{200, 230, 180, 96},
+			})
+		}
+	}
+}
+
+// EmitTrail leaves bubbles behind the tail, more of them the faster the fish swims.
+func (g *Game) EmitTrail(fish *Fish) {
+	if fish.Dead || rand.Float64() > trailRate*math.Hypot(fish.SpeedX, fish.SpeedY) {
+		return
+	}
+	tail := -fish.HalfWidth
+	if fish.FacingLeft {
+		tail = fish.HalfWidth
+	}
+	g.AddParticle(Particle{
+		Kind:   particleBubble,
+		X:      fish.X + 0.8*tail,
+		Y:      fish.Y + fish.HalfHeight*(rand.Float64()-0.5),
+		SpeedX: -0.2 * fish.SpeedX,
+		SpeedY: -0.3,
+		Size:   2 + fish.Size/40*rand.Float64(),
+		Plane:  fish.Plane,
+		Life:   60 + 60*rand.Float64(),
+		Color:  color.NRGBA{220, 240, 255, 160},
+	})
+}
+
+// Burst throws count particles out of a point in every direction.
+func (g *Game) Burst(kind int, x, y, plane, speed, size float64, count int, tint color.NRGBA, life float64) {
+	for i := 0; i < count; i++ {
+		angle, force := 2*math.Pi*rand.Float64(), speed*(0.5+rand.Float64())
+		g.AddParticle(Particle{
+			Kind:   kind,
+			X:      x,
+			Y:      y,
+			SpeedX: force * math.Cos(angle),
+			SpeedY: force * math.Sin(angle),
+			Size:   size * (0.6 + 0.8*rand.Float64()),
+			Angle:  angle,
+			Spin:   0.2 * (rand.Float64() - 0.5),
+			Plane:  plane,
+			Life:   life * (0.7 + 0.6*rand.Float64()),
+			Color:  tint,
+		})
+	}
+}
+
+// EmitParticles turns events into effects: a burst of bubbles and popping scales on a bite,
+// scales scattered all around a dead player and a cloud when a puffer inflates.
+func (g *Game) EmitParticles(event GameEvent) {
+	if g.headless {
+		return
+	}
+	switch event.Kind {
+	case eventEat:
+		prey := event.Fish
+		g.Burst(particleBubble, prey.X, prey.Y, prey.Plane, 1.5, 3, 8, color.NRGBA{220, 240, 255, 160}, 50)
+		g.Burst(particleScale, prey.X, prey.Y, prey.Plane, 2, 2+prey.Size/30, 6, g.FishColor(prey), 40)
+	case eventDeath:
+		if player := event.Player; player != nil {
+			g.Burst(particleScale, player.X, player.Y, player.Plane, 3, 3+player.Size/25, 40, g.FishColor(&player.Fish), 150)
+		}
+	case eventPuff:
+		puffer := event.Fish
+		g.Burst(particlePuff, puffer.X, puffer.Y, puffer.Plane, 1.2, puffer.HalfWidth/3, 16, color.NRGBA{230, 240, 250, 110}, 45)
+	}
+}
+
+// FishColor is the average color of the sprite under its tint, worked out once per sprite.
+func (g *Game) FishColor(fish *Fish) color.NRGBA {
+	base, ok := g.spriteColors[fish.Sprite]
+	if !ok {
+		base = averageColor(fish.Mask)
+		if g.spriteColors == nil {
+			g.spriteColors = map[string]color.NRGBA{}
+		}
+		g.spriteColors[fish.Sprite] = base
+	}
+	if fish.Tint != nil {
+		r, gr, b, _ := fish.Tint.RGBA()
+		base.R, base.G, base.B = uint8(uint32(base.R)*r/0xffff), uint8(uint32(base.G)*gr/0xffff), uint8(uint32(base.B)*b/0xffff)
+	}
+	return base
+}
+
+func averageColor(img image.Image) color.NRGBA {
+	var r, g, b, n uint64
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pr, pg, pb, pa := img.At(x, y).RGBA()
+			if pa < 0x8000 {
+				continue
+			}
+			r, g, b, n = r+uint64(pr*0xffff/pa), g+uint64(pg*0xffff/pa), b+uint64(pb*0xffff/pa), n+1
+		}
+	}
+	if n == 0 {
+		return color.NRGBA{255, 255, 255, 255}
+	}
+	return color.NRGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), 255}
+}
+
+// ParticlesTick moves every particle and frees the dead ones by swapping the last one into their slot.
+func (g *Game) ParticlesTick() {
+	left, top, right, bottom := g.View()
+	for i := 0; i < len(g.particles); i++ {
+		p := &g.particles[i]
+		switch p.Kind {
+		case particleBubble:
+			p.SpeedX *= 0.95
+			p.SpeedY = math.Max(p.SpeedY-0.02, -1.2)
+			p.X += 0.3 * math.Sin(p.Life/8)
+		case particleScale:
+			p.SpeedX *= 0.96
+			p.SpeedY = 0.96*p.SpeedY + 0.02
+			p.Angle += p.Spin
+		case particlePuff:
+			p.SpeedX *= 0.9
+			p.SpeedY *= 0.9
+			p.Size *= 1.02
+		case particlePlankton:
+			p.SpeedX = 0.99*p.SpeedX + 0.01*(rand.Float64()-0.5)
+			p.SpeedY = 0.99*p.SpeedY + 0.01*(rand.Float64()-0.5)
+			p.X = left + wrap(p.X-left, right-left)
+			p.Y = top + wrap(p.Y-top, bottom-top)
+		}
+		p.X += p.SpeedX
+		p.Y += p.SpeedY
+		if p.MaxLife == 0 {
+			continue
+		}
+		if p.Life--; p.Life <= 0 {
+			last := len(g.particles) - 1
+			g.particles[i] = g.particles[last]
+			g.particles = g.particles[:last]
+			i--
+		}
+	}
+}
+
+func wrap(value, size float64) float64 {
+	value = math.Mod(value, size)
+	if value < 0 {
+		value += size
+	}
+	return value
+}
+
+// DrawParticles draws the particles of one plane; like the fish, they get smaller and dimmer further back.
+func (g *Game) DrawParticles(plane int) {
+	if bubbleImage == nil {
+		bubbleImage, dotImage = ebiten.NewImage(16, 16), ebiten.NewImage(16, 16)
+		vector.StrokeCircle(bubbleImage, 8, 8, 6.5, 1.5, color.White, true)
+		vector.DrawFilledCircle(dotImage, 8, 8, 7, color.White, true)
+	}
+	depth := math.Pow(0.75, float64(plane))
+	op, camera := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}, g.CameraGeoM()
+	for i := range g.particles {
+		p := &g.particles[i]
+		if int(p.Plane) != plane {
+			continue
+		}
+		img, alpha, scaleX, scaleY := dotImage, depth, p.Size/8*depth, p.Size/8*depth
+		if p.MaxLife > 0 {
+			alpha *= math.Min(1, p.Life/particleFade)
+		}
+		switch p.Kind {
+		case particleBubble:
+			img = bubbleImage
+		case particleScale:
+			scaleY *= 0.5 + 0.3*math.Abs(math.Cos(p.Angle))
+		case particlePuff:
+			alpha *= p.Life / p.MaxLife
+		}
+		op.GeoM.Reset()
+		op.GeoM.Translate(-8, -8)
+		op.GeoM.Scale(scaleX, scaleY)
+		op.GeoM.Rotate(p.Angle)
+		op.GeoM.Translate(p.X, p.Y)
+		op.GeoM.Concat(camera)
+		op.ColorScale.Reset()
+		op.ColorScale.ScaleWithColor(p.Color)
+		op.ColorScale.ScaleAlpha(float32(alpha))
+		g.screen.DrawImage(img, op)
+	}
+}
+
+// DrawAllParticles is for screens that show no fish, like game over, where the scales of the player still settle.
+func (g *Game) DrawAllParticles() {
+	for plane := int(g.planeCount) - 1; plane >= 0; plane-- {
+		g.DrawParticles(plane)
+	}
+}
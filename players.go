@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+const (
+	maxPlayers = 4
+
+	playerCollisionsIgnore = 0
+	playerCollisionsEat    = 1
+	playerCollisionsBump   = 2
+)
+
+type InputDevice struct {
+	Keys       *Bindings
+	Mouse      bool
+	Gamepad    ebiten.GamepadID
+	HasGamepad bool
+}
+
+var playerTints = []color.RGBA{
+	{255, 255, 255, 255},
+	{255, 140, 140, 255},
+	{140, 255, 140, 255},
+	{255, 240, 120, 255},
+}
+
+// AssignDevices gives every player a gamepad if there are enough of them, and splits the keyboard between the first two players otherwise.
+// Online, only the local player gets a device; the others are driven by their peers.
+func (g *Game) AssignDevices() {
+	local := InputDevice{Keys: &g.bindings, Mouse: true, Gamepad: g.gamepadId, HasGamepad: g.hasGamepad}
+	if g.net != nil {
+		for i := range g.players {
+			g.players[i].Device = InputDevice{}
+		}
+		if g.net.local < len(g.players) {
+			g.players[g.net.local].Device = local
+		}
+		return
+	}
+	if len(g.players) == 1 {
+		g.players[0].Device = local
+		return
+	}
+	pads := g.gamepadIds
+	keyboardPlayers := len(g.players) - len(pads)
+	for i := range g.players {
+		device := InputDevice{}
+		switch {
+		case keyboardPlayers <= 0:
+			device.Gamepad, device.HasGamepad = pads[i], true
+			if i == 0 {
+				device.Keys, device.Mouse = &g.bindings, true
+			}
+		case i >= keyboardPlayers:
+			device.Gamepad, device.HasGamepad = pads[i-keyboardPlayers], true
+		case keyboardPlayers == 1:
+			device.Keys, device.Mouse = &g.bindings, true
+		case i == 0:
+			device.Keys, device.Mouse = &leftKeyboard, true
+		case i == 1:
+			device.Keys = &rightKeyboard
+		}
+		g.players[i].Device = device
+	}
+}
+
+func (g *Game) AllPlayersDead() bool {
+	for i := range g.players {
+		if !g.players[i].Dead {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Game) CheckGameOver() {
+	if g.AllPlayersDead() {
+		g.GameOver()
+	}
+}
+
+func (g *Game) CollidePlayers() {
+	if g.playerCollisions == playerCollisionsIgnore {
+		return
+	}
+	for i := range g.players {
+		for j := i + 1; j < len(g.players); j++ {
+			a, b := &g.players[i], &g.players[j]
+			if a.Dead || b.Dead || !a.Overlap(&b.Fish) {
+				continue
+			}
+			switch {
+			case g.playerCollisions == playerCollisionsEat && a.Size > b.Size:
+				a.Hit(&b.Fish)
+			case g.playerCollisions == playerCollisionsEat && b.Size > a.Size:
+				b.Hit(&a.Fish)
+			default:
+				a.Bump(b)
+			}
+		}
+	}
+}
+
+func (g *Game) DrawPlayerLabels() {
+	if len(g.players) < 2 {
+		return
+	}
+	face := g.GetFontFace("small", false)
+	for i := range g.players {
+		player := &g.players[i]
+		if player.Dead {
+			continue
+		}
+		label := fmt.Sprintf("P%d", i+1)
+		switch {
+		case g.net != nil && i == g.net.local:
+			label += " " + g.tr("(YOU)")
+		case g.net == nil && player.Device.Keys == nil && !player.Device.HasGamepad:
+			label += " " + g.tr("(NO CONTROLLER)")
+		}
+		op := &text.DrawOptions{}
+		x, y := g.ToScreen(player.X, player.Y-player.HalfHeight)
+		op.GeoM.Translate(x-g.Font("small")/2, y-g.Font("small")*1.2)
+		op.ColorScale.ScaleWithColor(player.Tint)
+		text.Draw(g.screen, label, face, op)
+	}
+}
+
+func (g *Game) DrawPlayerScores() {
+	if len(g.players) < 2 {
+		return
+	}
+	face := g.GetFontFace("small", true)
+	for i := range g.players {
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(0.4*g.screenWidth, 0.7*g.screenHeight+float64(i)*1.3*g.Font("small"))
+		op.ColorScale.ScaleWithColor(g.players[i].Tint)
+		text.Draw(g.screen, g.tr("P%d  EATEN: %0.0f  SCORE: %0.0f", i+1, g.players[i].Eaten, g.players[i].Score), face, op)
+	}
+}
+
+func (g *Game) GeneratePlayers() {
+	g.players = g.playerStaticArray[0:int(g.playerCount)]
+	for i := range g.players {
+		if g.players[i].game == nil {
+			g.players[i].Init(g, i)
+		}
+	}
+	g.AssignDevices()
+}
+
+// LeadPlayer is the biggest living player, whose depth sets the background colour.
+func (g *Game) LeadPlayer() *PlayerFish {
+	lead := &g.players[0]
+	for i := range g.players {
+		if !g.players[i].Dead && (lead.Dead || g.players[i].Size > lead.Size) {
+			lead = &g.players[i]
+		}
+	}
+	return lead
+}
+
+// Bump pushes two overlapping players apart, swapping their momentum.
+func (fish *PlayerFish) Bump(other *PlayerFish) {
+	fish.SpeedX, other.SpeedX = other.SpeedX, fish.SpeedX
+	fish.SpeedY, other.SpeedY = other.SpeedY, fish.SpeedY
+	dx, dy := other.X-fish.X, other.Y-fish.Y
+	distance := math.Max(math.Hypot(dx, dy), 1)
+	push := 4 / distance
+	fish.X, fish.Y = fish.X-dx*push, fish.Y-dy*push
+	other.X, other.Y = other.X+dx*push, other.Y+dy*push
+	fish.GraphUpdated, other.GraphUpdated = true, true
+}
+
+func (fish *PlayerFish) GamepadID() (ebiten.GamepadID, bool) {
+	return fish.Device.Gamepad, fish.Device.HasGamepad
+}
+
+func (fish *PlayerFish) isGamepadButtonsPressed(just bool, buttons ...ebiten.StandardGamepadButton) bool {
+	id, ok := fish.GamepadID()
+	return ok && isGamepadButtonsPressed(id, just, buttons...)
+}
@@ -0,0 +1,64 @@
+package main
+
+import "slices"
+
+// behaviours are the fish brains the game knows; a species can only reuse one of them.
+var behaviours = []string{"jelly", "bass", "goldfish", "puffer", "shark"}
+
+// Species is a kind of NPC fish: Behaviour is how it acts, Sprite how it looks and Share its part of the ocean.
+// Species without a share get exactly one fish each, like the jellyfish.
+type Species struct {
+	Name      string  `json:"name"`
+	Behaviour string  `json:"behaviour"`
+	Sprite    string  `json:"sprite"`
+	Share     float64 `json:"share"`
+}
+
+var baseSpecies = []Species{
+	{"jelly", "jelly", "jelly", 0},
+	{"bass", "bass", "bass", 0.35},
+	{"goldfish", "goldfish", "goldfish", 0.30},
+	{"puffer", "puffer", "puffer", 0.20},
+	{"shark", "shark", "shark", 0.15},
+}
+
+// getFishSpecies deals out the species by index: one fish for each species without a share first,
+// then the shares in order, with the last species taking whatever is left.
+func (g *Game) getFishSpecies(index, fishesCount float64) Species {
+	fixed := 0.0
+	for _, species := range g.species {
+		if species.Share == 0 {
+			if index == fixed {
+				return species
+			}
+			fixed++
+		}
+	}
+	cumulative, last := 0.0, g.species[len(g.species)-1]
+	for _, species := range g.species {
+		if species.Share == 0 {
+			continue
+		}
+		cumulative += species.Share
+		last = species
+		if index < cumulative*fishesCount {
+			return species
+		}
+	}
+	return last
+}
+
+func (g *Game) FindSpecies(name string) (Species, bool) {
+	i := slices.IndexFunc(g.species, func(species Species) bool { return species.Name == name })
+	if i < 0 {
+		return Species{}, false
+	}
+	return g.species[i], true
+}
+
+func (g *Game) SpeciesNames() (names []string) {
+	for _, species := range g.species {
+		names = append(names, species.Name)
+	}
+	return
+}
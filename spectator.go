@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	spectatorInterval = 2
+	spectatorBacklog  = 8
+	websocketGUID     = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	websocketText  = 0x1
+	websocketClose = 0x8
+	websocketPing  = 0x9
+	websocketPong  = 0xA
+
+	websocketProtocolError = 1002
+)
+
+var errUnmaskedFrame = errors.New("unmasked websocket frame from a client")
+
+//go:embed resources/viewer.html
+var viewerPage []byte
+
+var gameStateNames = map[int]string{
+	gameRunning:      "running",
+	gameOver:         "over",
+	gameVictory:      "victory",
+	gameMenu:         "menu",
+	gameOptionsMenu:  "options",
+	gameControlsMenu: "controls",
+	gameLobby:        "lobby",
+	gameAchievements: "achievements",
+	gameStatistics:   "statistics",
+}
+
+type SnapshotFish struct {
+	Type       string  `json:"type"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Size       float64 `json:"size"`
+	Plane      float64 `json:"plane"`
+	FacingLeft bool    `json:"facing_left"`
+	Dead       bool    `json:"dead"`
+}
+
+type SnapshotPlayer struct {
+	SnapshotFish
+	Index int     `json:"index"`
+	Stage string  `json:"stage"`
+	Score float64 `json:"score"`
+	Eaten float64 `json:"eaten"`
+	Combo float64 `json:"combo"`
+}
+
+// GameSnapshot is one frame of the spectator stream; coordinates are in the ocean, Width by Height, which is up to three screens each way.
+type GameSnapshot struct {
+	Tick      int              `json:"tick"`
+	State     string           `json:"state"`
+	Width     float64          `json:"width"`
+	Height    float64          `json:"height"`
+	Planes    float64          `json:"planes"`
+	Score     float64          `json:"score"`
+	Eaten     float64          `json:"eaten"`
+	HighScore float64          `json:"high_score"`
+	Players   []SnapshotPlayer `json:"players"`
+	Fish      []SnapshotFish   `json:"fish"`
+}
+
+// SpectatorServer fans snapshots out to every connected viewer; a viewer that falls behind just misses frames.
+type SpectatorServer struct {
+	mutex   sync.Mutex
+	clients map[chan []byte]struct{}
+	latest  []byte
+	ticks   int
+}
+
+func snapshotFish(fish *Fish) SnapshotFish {
+	return SnapshotFish{Type: fish.Type, X: fish.X, Y: fish.Y, Size: fish.Size, Plane: fish.Plane, FacingLeft: fish.FacingLeft, Dead: fish.Dead}
+}
+
+func (g *Game) Snapshot(tick int) GameSnapshot {
+	snapshot := GameSnapshot{
+		Tick:      tick,
+		State:     gameStateNames[g.gameState],
+		Width:     g.worldWidth,
+		Height:    g.worldHeight,
+		Planes:    g.planeCount,
+		Score:     g.score,
+		Eaten:     g.eaten,
+		HighScore: g.highScore,
+		Players:   []SnapshotPlayer{},
+		Fish:      make([]SnapshotFish, 0, len(g.fish)),
+	}
+	if g.gameState == gameRunning || g.gameState == gameOver || g.gameState == gameVictory {
+		for i := range g.players {
+			player := &g.players[i]
+			snapshot.Players = append(snapshot.Players, SnapshotPlayer{
+				SnapshotFish: snapshotFish(&player.Fish),
+				Index:        player.Index,
+				Stage:        growthStages[player.Stage].Name,
+				Score:        player.Score,
+				Eaten:        player.Eaten,
+				Combo:        player.ComboMultiplier(),
+			})
+		}
+	}
+	for i := range g.fish {
+		snapshot.Fish = append(snapshot.Fish, snapshotFish(&g.fish[i]))
+	}
+	return snapshot
+}
+
+// PublishSnapshot sends the state to viewers every few ticks; it runs on the game loop, so the snapshot is always consistent.
+func (g *Game) PublishSnapshot() {
+	s := g.spectators
+	s.ticks++
+	if s.ticks%spectatorInterval != 0 {
+		return
+	}
+	data, err := json.Marshal(g.Snapshot(s.ticks))
+	if err != nil {
+		log.Printf("spectator snapshot: %v", err)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.latest = data
+	for client := range s.clients {
+		select {
+		case client <- data:
+		default:
+		}
+	}
+}
+
+// ServeSpectators starts the viewer page on /, the WebSocket stream on /ws and the latest snapshot on /state.
+func (g *Game) ServeSpectators(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s := &SpectatorServer{clients: map[chan []byte]struct{}{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(viewerPage)
+	})
+	mux.HandleFunc("/state", func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		data := s.latest
+		s.mutex.Unlock()
+		if data == nil {
+			http.Error(w, "no snapshot yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	mux.HandleFunc("/ws", s.ServeWebSocket)
+	g.spectators = s
+	log.Printf("spectator stream on http://%s/", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("spectator server: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *SpectatorServer) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+		return
+	}
+	conn, rw, err := acceptWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+	frames := make(chan []byte, spectatorBacklog)
+	closed := make(chan struct{})
+	s.mutex.Lock()
+	s.clients[frames] = struct{}{}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, frames)
+		s.mutex.Unlock()
+	}()
+	var writeMutex sync.Mutex
+	write := func(opcode byte, payload []byte) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		if err := writeWebSocketFrame(rw.Writer, opcode, payload); err != nil {
+			return err
+		}
+		return rw.Flush()
+	}
+	var closeStatus []byte
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := readWebSocketFrame(rw.Reader, true)
+			if errors.Is(err, errUnmaskedFrame) {
+				closeStatus = binary.BigEndian.AppendUint16(nil, websocketProtocolError)
+			}
+			if err != nil || opcode == websocketClose {
+				return
+			}
+			if opcode == websocketPing {
+				write(websocketPong, payload)
+			}
+		}
+	}()
+	for {
+		select {
+		case data := <-frames:
+			if err := write(websocketText, data); err != nil {
+				return
+			}
+		case <-closed:
+			write(websocketClose, closeStatus)
+			return
+		}
+	}
+}
+
+// sameOrigin lets through clients that send no Origin, like WatchSpectators or curl, and pages served by this very host;
+// any other web page the user happens to have open must not reach the local servers.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.ReadWriter, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		return nil, nil, errors.New("expected a WebSocket upgrade")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection cannot be upgraded")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, rw, nil
+}
+
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// writeWebSocketFrame writes one unfragmented, unmasked frame, which is what a server sends.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readWebSocketFrame reads one frame and unmasks it; continuation frames are returned as they come.
+// Frames from a client have to be masked, so a server refuses the rest (RFC 6455, section 5.1).
+func readWebSocketFrame(r io.Reader, fromClient bool) (opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return
+	}
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if fromClient && !masked {
+		return 0, nil, errUnmaskedFrame
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err = io.ReadFull(r, extended); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err = io.ReadFull(r, extended); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > 1<<20 {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+	mask := make([]byte, 4)
+	if masked {
+		if _, err = io.ReadFull(r, mask); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// WatchSpectators connects to a spectator stream and prints every frame as a JSON line, as a client for checking the stream without a browser.
+func WatchSpectators(addr string, out io.Writer) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	key := base64.StdEncoding.EncodeToString([]byte("fish30d-spectate"))
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", addr, key)
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return fmt.Errorf("spectator handshake failed: %s", response.Status)
+	}
+	for {
+		opcode, payload, err := readWebSocketFrame(reader, false)
+		if err != nil {
+			return err
+		}
+		if opcode == websocketClose {
+			return nil
+		}
+		if opcode == websocketText {
+			if _, err := fmt.Fprintf(out, "%s\n", payload); err != nil {
+				return err
+			}
+		}
+	}
+}
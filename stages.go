@@ -0,0 +1,148 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+const evolutionFrames = 45
+
+type GrowthStage struct {
+	Name         string
+	MinSize      float64
+	Sprite       string
+	Hue          float64
+	Saturation   float64
+	Value        float64
+	Acceleration float64
+	Friction     float64
+	TurnRate     float64
+}
+
+type PlayerAbility struct {
+	Name    string
+	MinSize float64
+}
+
+var (
+	growthStages = []GrowthStage{
+		{Name: "fry", MinSize: 0, Sprite: "player", Hue: 0, Saturation: 1, Value: 1, Acceleration: 1.2, Friction: 1.2, TurnRate: 0.5},
+		{Name: "juvenile", MinSize: 20, Sprite: "player-juvenile", Hue: 0.6, Saturation: 1.2, Value: 1, Acceleration: 1, Friction: 1, TurnRate: 0.35},
+		{Name: "adult", MinSize: 50, Sprite: "player-adult", Hue: 2.2, Saturation: 1.3, Value: 0.9, Acceleration: 0.9, Friction: 0.8, TurnRate: 0.25},
+		{Name: "elder", MinSize: 100, Sprite: "player-elder", Hue: 3.6, Saturation: 0.6, Value: 0.8, Acceleration: 0.8, Friction: 0.6, TurnRate: 0.15},
+	}
+	playerAbilities = []PlayerAbility{
+		{Name: "dash", MinSize: 25},
+		{Name: "thick scales", MinSize: 60},
+	}
+)
+
+func getGrowthStage(size float64) (index int) {
+	for i, stage := range growthStages {
+		if size >= stage.MinSize {
+			index = i
+		}
+	}
+	return
+}
+
+func recolorImage(src image.Image, hue, saturation, value float64) image.Image {
+	var cm colorm.ColorM
+	cm.ChangeHSV(hue, saturation, value)
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := src.At(x, y)
+			if _, _, _, a := c.RGBA(); a == 0 {
+				continue
+			}
+			dst.Set(x, y, color.RGBAModel.Convert(cm.Apply(c)))
+		}
+	}
+	return dst
+}
+
+func (fish *PlayerFish) ApplyStage(evolve bool) {
+	stage := growthStages[fish.Stage]
+	if fish.Sprite != stage.Sprite {
+		fish.Sprite = stage.Sprite
+		fish.InitImage()
+		fish.ResizeSprite()
+	}
+	if evolve {
+		fish.Glow = evolutionFrames
+		fish.GraphUpdated = true
+	}
+	fish.UpdateStats()
+}
+
+func (fish *PlayerFish) Dash(driveX, driveY float64) {
+	if fish.DashCooldown > 0 || !fish.HasAbility("dash") || (driveX == 0 && driveY == 0) {
+		return
+	}
+	boost := 12 * fish.AccelerationCoefficient
+	fish.SpeedX += driveX * boost
+	fish.SpeedY += driveY * boost
+	fish.DashCooldown = 3 * 60
+}
+
+func (fish *PlayerFish) Grow(delta float64) {
+	fish.SetSize(fish.Size + delta)
+	if stage := getGrowthStage(fish.Size); stage != fish.Stage {
+		evolved := stage > fish.Stage
+		fish.Stage = stage
+		fish.ApplyStage(evolved)
+	} else {
+		fish.UpdateStats()
+	}
+}
+
+func (fish *PlayerFish) HasAbility(name string) bool {
+	for _, ability := range playerAbilities {
+		if ability.Name == name {
+			return fish.Size >= ability.MinSize
+		}
+	}
+	return false
+}
+
+// Steer turns the actual drive towards the requested one as fast as the current stage allows.
+func (fish *PlayerFish) Steer(driveX, driveY float64) (float64, float64) {
+	turnRate := fish.TurnRate
+	if turnRate <= 0 {
+		turnRate = 1
+	}
+	fish.DriveX += (driveX - fish.DriveX) * turnRate
+	fish.DriveY += (driveY - fish.DriveY) * turnRate
+	return fish.DriveX, fish.DriveY
+}
+
+func (fish *PlayerFish) StageTick() {
+	if fish.DashCooldown > 0 {
+		fish.DashCooldown--
+	}
+	if fish.Glow > 0 {
+		fish.Glow--
+		fish.GraphUpdated = true
+	}
+}
+
+// UpdateStats interpolates the stage stats towards the next stage, so growth inside a stage is gradual too.
+func (fish *PlayerFish) UpdateStats() {
+	stage := growthStages[fish.Stage]
+	acceleration, friction, turnRate := stage.Acceleration, stage.Friction, stage.TurnRate
+	if fish.Stage+1 < len(growthStages) {
+		next := growthStages[fish.Stage+1]
+		progress := math.Min((fish.Size-stage.MinSize)/(next.MinSize-stage.MinSize), 1)
+		acceleration += (next.Acceleration - acceleration) * progress
+		friction += (next.Friction - friction) * progress
+		turnRate += (next.TurnRate - turnRate) * progress
+	}
+	fish.AccelerationCoefficient = acceleration
+	fish.FrictionCoefficient = friction
+	fish.TurnRate = turnRate
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	statsFile          = "stats.json"
+	sizeSampleFrames   = 60
+	maxSizeSamples     = 600
+	nearMissRange      = 1.3
+	nearMissClearRange = 2
+)
+
+// sizeBuckets are the lower bounds of the "eaten by size" groups.
+var sizeBuckets = []float64{0, 10, 20, 40}
+
+// RunStats is used both for a single run and, added up, for the lifetime totals.
+type RunStats struct {
+	Runs           int            `json:"runs"`
+	EatenBySpecies map[string]int `json:"eaten_by_species"`
+	EatenBySize    map[string]int `json:"eaten_by_size"`
+	DeathsByKiller map[string]int `json:"deaths_by_killer"`
+	DeathsByPlane  map[string]int `json:"deaths_by_plane"`
+	TicksAlive     int            `json:"ticks_alive"`
+	Distance       float64        `json:"distance"`
+	PlaneSwitches  int            `json:"plane_switches"`
+	PeakSize       float64        `json:"peak_size"`
+	NearMisses     int            `json:"near_misses"`
+	SizeCurve      []float64      `json:"size_curve"`
+}
+
+type Statistics struct {
+	Run      RunStats
+	Lifetime RunStats
+	recorded bool
+	ticks    int
+	nearby   map[[2]*Fish]bool
+}
+
+func newRunStats() RunStats {
+	return RunStats{EatenBySpecies: map[string]int{}, EatenBySize: map[string]int{}, DeathsByKiller: map[string]int{}, DeathsByPlane: map[string]int{}}
+}
+
+func sizeBucket(size float64) string {
+	i := len(sizeBuckets) - 1
+	for i > 0 && size < sizeBuckets[i] {
+		i--
+	}
+	if i == len(sizeBuckets)-1 {
+		return fmt.Sprintf("%0.0f+", sizeBuckets[i])
+	}
+	return fmt.Sprintf("%0.0f-%0.0f", sizeBuckets[i], sizeBuckets[i+1]-1)
+}
+
+func (g *Game) LoadStatistics() {
+	g.statistics = Statistics{Run: newRunStats(), Lifetime: newRunStats(), recorded: true, nearby: map[[2]*Fish]bool{}}
+	if err := loadConfig(statsFile, &g.statistics.Lifetime); err != nil {
+		log.Printf("statistics: %v", err)
+	}
+}
+
+// Handle keeps the current run up to date and folds it into the lifetime totals once the run is over.
+func (s *Statistics) Handle(g *Game, event GameEvent) {
+	if !g.TracksProgress(event.Player) {
+		return
+	}
+	run := &s.Run
+	switch event.Kind {
+	case eventStart:
+		s.Record()
+		s.Run, s.recorded, s.ticks = newRunStats(), false, 0
+		clear(s.nearby)
+	case eventTick:
+		s.Tick(g)
+	case eventEat:
+		run.EatenBySpecies[event.Fish.Type]++
+		run.EatenBySize[sizeBucket(event.Fish.Size)]++
+	case eventDeath:
+		run.DeathsByKiller[event.Fish.Type]++
+		run.DeathsByPlane[fmt.Sprintf("plane %0.0f", event.Player.Plane+1)]++
+	case eventPlaneSwitch:
+		run.PlaneSwitches++
+	case eventGameOver, eventVictory, eventLeave:
+		s.Record()
+	}
+}
+
+// Tick measures the local players: time alive, distance, size and the bigger fish that only just missed them.
+func (s *Statistics) Tick(g *Game) {
+	run := &s.Run
+	alive, size := false, 0.0
+	for i := range g.players {
+		player := &g.players[i]
+		if !g.TracksProgress(player) || player.Dead {
+			continue
+		}
+		alive, size = true, math.Max(size, player.Size)
+		run.Distance += math.Hypot(player.SpeedX, player.SpeedY)
+		run.PeakSize = math.Max(run.PeakSize, player.Size)
+		for j := range g.fish {
+			fish := &g.fish[j]
+			key := [2]*Fish{&player.Fish, fish}
+			reach := player.HalfWidth + fish.HalfWidth
+			distance := math.Hypot(fish.X-player.X, fish.Y-player.Y)
+			switch {
+			case fish.Dead || fish.Plane != player.Plane || fish.Size <= player.Size:
+				delete(s.nearby, key)
+			case distance < nearMissRange*reach:
+				s.nearby[key] = true
+			case distance > nearMissClearRange*reach && s.nearby[key]:
+				run.NearMisses++
+				delete(s.nearby, key)
+			}
+		}
+	}
+	if !alive {
+		return
+	}
+	run.TicksAlive++
+	if s.ticks++; s.ticks%sizeSampleFrames == 1 {
+		run.SizeCurve = append(run.SizeCurve, size)
+		if len(run.SizeCurve) > maxSizeSamples {
+			run.SizeCurve = halveCurve(run.SizeCurve)
+		}
+	}
+}
+
+// Record adds the run to the lifetime totals once; the lifetime keeps the size curve of its biggest run.
+func (s *Statistics) Record() {
+	if s.recorded {
+		return
+	}
+	s.recorded = true
+	run, life := &s.Run, &s.Lifetime
+	life.Runs++
+	for _, pair := range [][2]map[string]int{
+		{life.EatenBySpecies, run.EatenBySpecies},
+		{life.EatenBySize, run.EatenBySize},
+		{life.DeathsByKiller, run.DeathsByKiller},
+		{life.DeathsByPlane, run.DeathsByPlane},
+	} {
+		for key, count := range pair[1] {
+			pair[0][key] += count
+		}
+	}
+	life.TicksAlive += run.TicksAlive
+	life.Distance += run.Distance
+	life.PlaneSwitches += run.PlaneSwitches
+	life.NearMisses += run.NearMisses
+	if run.PeakSize > life.PeakSize {
+		life.PeakSize, life.SizeCurve = run.PeakSize, run.SizeCurve
+	}
+	if err := saveConfig(statsFile, life); err != nil {
+		log.Printf("statistics: %v", err)
+	}
+}
+
+func halveCurve(curve []float64) []float64 {
+	halved := curve[:0]
+	for i := 0; i+1 < len(curve); i += 2 {
+		halved = append(halved, math.Max(curve[i], curve[i+1]))
+	}
+	return halved
+}
+
+func (g *Game) countsString(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	slices.SortStableFunc(keys, func(a, b string) int { return counts[b] - counts[a] })
+	parts := []string{}
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s %d", g.tr(key), counts[key]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func total(counts map[string]int) (sum int) {
+	for _, count := range counts {
+		sum += count
+	}
+	return
+}
+
+func (stats *RunStats) Lines(g *Game) []string {
+	seconds := stats.TicksAlive / 60
+	return []string{
+		g.tr("Time alive: %d:%02d", seconds/60, seconds%60),
+		g.tr("Distance swum: %0.1f screens", stats.Distance/screenWidth),
+		g.tr("Fish eaten: %d", total(stats.EatenBySpecies)),
+		"  " + g.countsString(stats.EatenBySpecies),
+		"  " + g.tr("by size: %s", g.countsString(stats.EatenBySize)),
+		g.tr("Deaths: %d", total(stats.DeathsByKiller)),
+		"  " + g.tr("by: %s", g.countsString(stats.DeathsByKiller)),
+		"  " + g.tr("on: %s", g.countsString(stats.DeathsByPlane)),
+		g.tr("Plane switches: %d", stats.PlaneSwitches),
+		g.tr("Peak size: %0.0f", stats.PeakSize),
+		g.tr("Near misses: %d", stats.NearMisses),
+	}
+}
+
+func (g *Game) DrawStatistics() {
+	g.DrawAllNpcFish()
+	s := &g.statistics
+	face := g.GetFontFace("small", false)
+	titleFace := g.GetFontFace("medium", true)
+	columns := []struct {
+		title string
+		stats *RunStats
+	}{{g.tr("LAST RUN"), &s.Run}, {g.trn("LIFETIME (%d RUNS)", s.Lifetime.Runs, s.Lifetime.Runs), &s.Lifetime}}
+	for i, column := range columns {
+		x := (0.05 + 0.48*float64(i)) * g.screenWidth
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(x, 0.04*g.screenHeight)
+		text.Draw(g.screen, column.title, titleFace, op)
+		for j, line := range column.stats.Lines(g) {
+			op := &text.DrawOptions{}
+			op.GeoM.Translate(x, 0.12*g.screenHeight+float64(j)*1.25*g.Font("small"))
+			text.Draw(g.screen, line, face, op)
+		}
+		g.DrawSizeCurve(column.stats.SizeCurve, x, 0.62*g.screenHeight, 0.42*g.screenWidth, 0.22*g.screenHeight)
+	}
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(0.05*g.screenWidth, 0.88*g.screenHeight)
+	op.ColorScale.ScaleWithColor(color.RGBA{255, 200, 0, 255})
+	killer := g.tr("nothing yet")
+	if len(s.Lifetime.DeathsByKiller) > 0 {
+		killer = strings.SplitN(g.countsString(s.Lifetime.DeathsByKiller), ",", 2)[0]
+	}
+	text.Draw(g.screen, g.tr("What kills you most: %s", killer), face, op)
+}
+
+// DrawSizeCurve plots size against time, one sample per second, scaled to fit the box.
+func (g *Game) DrawSizeCurve(curve []float64, x, y, width, height float64) {
+	outline := color.RGBA{255, 255, 255, 96}
+	vector.StrokeRect(g.screen, float32(x), float32(y), float32(width), float32(height), 1, outline, false)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x, y-1.2*g.Font("small"))
+	text.Draw(g.screen, g.tr("Size over time"), g.GetFontFace("small", false), op)
+	if len(curve) < 2 {
+		return
+	}
+	peak := slices.Max(curve)
+	point := func(i int) (float32, float32) {
+		return float32(x + width*float64(i)/float64(len(curve)-1)), float32(y + height*(1-curve[i]/peak))
+	}
+	for i := 1; i < len(curve); i++ {
+		x0, y0 := point(i - 1)
+		x1, y1 := point(i)
+		vector.StrokeLine(g.screen, x0, y0, x1, y1, 2, color.RGBA{255, 200, 0, 255}, true)
+	}
+}
+
+func (g *Game) GoToStatistics() {
+	g.gameState = gameStatistics
+}
+
+func (g *Game) StatisticsCycle() error {
+	for i := range g.fish {
+		g.fish[i].Move()
+	}
+	if g.IsActionPressed(actionBack, true) || g.IsActionPressed(actionConfirm, true) {
+		g.GoToMenu(false)
+	}
+	return nil
+}
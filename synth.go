@@ -0,0 +1,158 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+)
+
+const synthFile = "synth.json"
+
+//go:embed resources/synth.json
+var synthPresetData []byte
+
+// Envelope is a classic ADSR; times are in seconds and Sustain is a level between 0 and 1.
+type Envelope struct {
+	Attack  float64 `json:"attack"`
+	Decay   float64 `json:"decay"`
+	Sustain float64 `json:"sustain"`
+	Release float64 `json:"release"`
+}
+
+// Voice is one oscillator starting Delay seconds into the sound. With Notes it plays them one after another,
+// NoteLength each, and restarts its envelope and sweep on every note; Loop repeats the notes until the sound ends.
+// Without Notes it plays Freq to the end.
+type Voice struct {
+	Wave         string    `json:"wave"`
+	Delay        float64   `json:"delay"`
+	Freq         float64   `json:"freq"`
+	FreqEnd      float64   `json:"freq_end"`
+	Curve        float64   `json:"curve"`
+	Notes        []float64 `json:"notes"`
+	NoteLength   float64   `json:"note_length"`
+	Loop         bool      `json:"loop"`
+	Gain         float64   `json:"gain"`
+	Cutoff       float64   `json:"cutoff"`
+	Tremolo      float64   `json:"tremolo"`
+	TremoloDepth float64   `json:"tremolo_depth"`
+	Envelope     Envelope  `json:"envelope"`
+}
+
+type SynthPreset struct {
+	Duration float64 `json:"duration"`
+	Voices   []Voice `json:"voices"`
+}
+
+// loadSynthPresets reads the built-in presets, then lets synth.json in the config directory retune any of them.
+func loadSynthPresets() map[string]SynthPreset {
+	presets := map[string]SynthPreset{}
+	if err := json.Unmarshal(synthPresetData, &presets); err != nil {
+		log.Fatal(err)
+	}
+	if err := loadConfig(synthFile, &presets); err != nil {
+		log.Printf("synth: %v", err)
+	}
+	return presets
+}
+
+// Level is the envelope at time t into a note lasting length seconds.
+func (e Envelope) Level(t, length float64) float64 {
+	switch {
+	case t < 0 || t >= length:
+		return 0
+	case t > length-e.Release:
+		return e.SustainLevel(length-e.Release) * (length - t) / e.Release
+	}
+	return e.SustainLevel(t)
+}
+
+func (e Envelope) SustainLevel(t float64) float64 {
+	switch {
+	case t < e.Attack:
+		return t / e.Attack
+	case t < e.Attack+e.Decay:
+		return 1 - (1-e.Sustain)*(t-e.Attack)/e.Decay
+	}
+	return e.Sustain
+}
+
+func oscillator(wave string, phase float64, rng *rand.Rand) float64 {
+	phase -= math.Floor(phase)
+	switch wave {
+	case "square":
+		if phase < 0.5 {
+			return 1
+		}
+		return -1
+	case "saw":
+		return 2*phase - 1
+	case "triangle":
+		return 1 - 4*math.Abs(phase-0.5)
+	case "noise":
+		return 2*rng.Float64() - 1
+	}
+	return math.Sin(2 * math.Pi * phase)
+}
+
+// Synthesize renders a preset to mono samples between -1 and 1. It is deterministic, noise included.
+func Synthesize(preset SynthPreset, sampleRate int) []float64 {
+	samples := make([]float64, int(preset.Duration*float64(sampleRate)))
+	rng := rand.New(rand.NewSource(1))
+	for _, voice := range preset.Voices {
+		length, sequenced := preset.Duration-voice.Delay, len(voice.Notes) > 0 && voice.NoteLength > 0
+		if sequenced {
+			length = voice.NoteLength
+		}
+		curve := voice.Curve
+		if curve == 0 {
+			curve = 1
+		}
+		alpha := 1.0
+		if voice.Cutoff > 0 {
+			alpha = 1 - math.Exp(-2*math.Pi*voice.Cutoff/float64(sampleRate))
+		}
+		phase, filtered, note := 0.0, 0.0, -1
+		for i := int(voice.Delay * float64(sampleRate)); i < len(samples); i++ {
+			t := float64(i)/float64(sampleRate) - voice.Delay
+			freq, noteTime := voice.Freq, t
+			if sequenced {
+				index := int(t / length)
+				if index >= len(voice.Notes) && !voice.Loop {
+					break
+				}
+				if index != note {
+					note, phase = index, 0
+				}
+				freq, noteTime = voice.Notes[index%len(voice.Notes)], t-float64(index)*length
+			}
+			if voice.FreqEnd > 0 {
+				freq += (voice.FreqEnd - freq) * math.Pow(noteTime/length, curve)
+			}
+			phase += freq / float64(sampleRate)
+			sample := oscillator(voice.Wave, phase, rng) * voice.Gain * voice.Envelope.Level(noteTime, length)
+			if voice.Tremolo > 0 {
+				sample *= 1 - voice.TremoloDepth*(0.5+0.5*math.Sin(2*math.Pi*voice.Tremolo*t))
+			}
+			filtered += alpha * (sample - filtered)
+			samples[i] += filtered
+		}
+	}
+	for i, sample := range samples {
+		samples[i] = math.Max(-1, math.Min(1, sample))
+	}
+	return samples
+}
+
+// pcmStereo turns samples into the 16-bit little-endian stereo frames ebiten/audio plays.
+func pcmStereo(samples []float64) []byte {
+	pcm := make([]byte, 4*len(samples))
+	for i, sample := range samples {
+		value := uint16(int16(sample * math.MaxInt16))
+		binary.LittleEndian.PutUint16(pcm[4*i:], value)
+		binary.LittleEndian.PutUint16(pcm[4*i+2:], value)
+	}
+	return pcm
+}
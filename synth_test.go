@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestEnvelopeLevel(t *testing.T) {
+	envelope := Envelope{Attack: 0.1, Decay: 0.2, Sustain: 0.5, Release: 0.3}
+	tests := []struct {
+		name  string
+		t     float64
+		level float64
+	}{
+		{"before the note", -0.01, 0},
+		{"start", 0, 0},
+		{"mid-attack", 0.05, 0.5},
+		{"attack peak", 0.1, 1},
+		{"mid-decay", 0.2, 0.75},
+		{"end of decay", 0.3, 0.5},
+		{"sustain", 0.5, 0.5},
+		{"release start", 0.7, 0.5},
+		{"mid-release", 0.85, 0.25},
+		{"end of the note", 1, 0},
+		{"after the note", 1.5, 0},
+	}
+	for _, test := range tests {
+		if level := envelope.Level(test.t, 1); math.Abs(level-test.level) > 1e-9 {
+			t.Errorf("%s: Level(%v) = %v, want %v", test.name, test.t, level, test.level)
+		}
+	}
+}
+
+func TestEnvelopeReleaseFromAttack(t *testing.T) {
+	envelope := Envelope{Attack: 0.5, Decay: 0.1, Sustain: 0.8, Release: 0.2}
+	// The note ends mid-attack, so the release fades from the level the attack got to, not from the sustain.
+	if level := envelope.Level(0.2, 0.4); math.Abs(level-0.4) > 1e-9 {
+		t.Errorf("Level at the release start = %v, want 0.4", level)
+	}
+	if level := envelope.Level(0.3, 0.4); math.Abs(level-0.2) > 1e-9 {
+		t.Errorf("Level mid-release = %v, want 0.2", level)
+	}
+}
+
+func builtinPresets(t *testing.T) map[string]SynthPreset {
+	t.Helper()
+	presets := map[string]SynthPreset{}
+	if err := json.Unmarshal(synthPresetData, &presets); err != nil {
+		t.Fatal(err)
+	}
+	return presets
+}
+
+func TestSynthesizePresets(t *testing.T) {
+	for name, preset := range builtinPresets(t) {
+		samples := Synthesize(preset, audioSampleRate)
+		if want := int(preset.Duration * audioSampleRate); len(samples) != want {
+			t.Errorf("%s: %d samples, want %d", name, len(samples), want)
+		}
+		silent := true
+		for i, sample := range samples {
+			if sample < -1 || sample > 1 || math.IsNaN(sample) {
+				t.Errorf("%s: sample %d is %v, outside [-1, 1]", name, i, sample)
+				break
+			}
+			silent = silent && sample == 0
+		}
+		if silent {
+			t.Errorf("%s is silent", name)
+		}
+	}
+}
+
+func TestSynthesizeIsDeterministic(t *testing.T) {
+	for name, preset := range builtinPresets(t) {
+		if !slices.Equal(Synthesize(preset, audioSampleRate), Synthesize(preset, audioSampleRate)) {
+			t.Errorf("%s renders differently twice", name)
+		}
+	}
+}
+
+func TestPCMStereo(t *testing.T) {
+	samples := []float64{0, 1, -1, 0.5}
+	pcm := pcmStereo(samples)
+	if len(pcm) != 4*len(samples) {
+		t.Fatalf("%d bytes, want %d", len(pcm), 4*len(samples))
+	}
+	for i, want := range []int16{0, math.MaxInt16, -math.MaxInt16, math.MaxInt16 / 2} {
+		left, right := int16(binary.LittleEndian.Uint16(pcm[4*i:])), int16(binary.LittleEndian.Uint16(pcm[4*i+2:]))
+		if left != want || right != want {
+			t.Errorf("frame %d = %d, %d, want %d on both channels", i, left, right, want)
+		}
+	}
+}
+
+func TestRepitch(t *testing.T) {
+	clip := pcmStereo(Synthesize(builtinPresets(t)["gulp"], audioSampleRate))
+	frames := len(clip) / 4
+	tests := []struct {
+		pitch  float64
+		frames int
+	}{
+		{1, frames},
+		{2, frames / 2},
+		{0.5, 2 * frames},
+		{1.5, int(float64(frames) / 1.5)},
+	}
+	for _, test := range tests {
+		if got := len(repitch(clip, test.pitch)); got != 4*test.frames {
+			t.Errorf("repitch(%v) is %d bytes, want %d", test.pitch, got, 4*test.frames)
+		}
+	}
+	if !slices.Equal(repitch(clip, 1), clip) {
+		t.Error("repitch(1) changed the clip")
+	}
+}
+
+func TestMuffle(t *testing.T) {
+	clip := pcmStereo(Synthesize(builtinPresets(t)["sting"], audioSampleRate))
+	if muffled := muffle(clip); len(muffled) != len(clip) {
+		t.Errorf("muffle is %d bytes, want %d", len(muffled), len(clip))
+	}
+	silence := make([]byte, 400)
+	if !slices.Equal(muffle(silence), silence) {
+		t.Error("muffle made noise out of silence")
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+const (
+	tipsFile        = "tips.json"
+	earlyDeathTicks = 30 * 60
+	earlyDeathRuns  = 3
+)
+
+// Tip is shown on the game over screen when all of its tags hold. Tips come in order of usefulness, jokes last.
+type Tip struct {
+	ID   string
+	Text string
+	Tags []string
+}
+
+var tips = []Tip{
+	{"shark-range", "Sharks lunge at anything between half and one and a half times their size.", []string{"killer:shark"}},
+	{"shark-lunge", "A lunging shark turns back after a second. Dodge to the other plane and wait it out.", []string{"killer:shark"}},
+	{"shark-meal", "Sharks attack anything they see as a good meal, for better or worse.", []string{"killer:shark"}},
+	{"puffer-wait", "Pufferfish puff up when scared and become too big to swallow. Wait until they deflate.", []string{"killer:puffer"}},
+	{"puffer-treat", "A puffed-up puffer is worth more, if you are big enough to swallow it.", []string{"killer:puffer"}},
+	{"bass-bigger", "A bass that doesn't run from you is a bass that is bigger than you.", []string{"killer:bass"}},
+	{"bass-sneak", "The bass will run away when threatened, but will always try to sneak back.", []string{"killer:bass"}},
+	{"goldfish-dodge", "A startled goldfish dodges to the other plane. Mind where it comes out.", []string{"killer:goldfish"}},
+	{"jelly-sideways", "Jellyfish only drift up and down. Cross their path sideways.", []string{"killer:jelly"}},
+	{"player-bigger", "Your friends are fish too. The bigger one eats the smaller.", []string{"killer:player"}},
+	{"player-bump", "Set player collisions to bump in Options for a friendlier game.", []string{"killer:player"}},
+	{"plane-dodge", "If you're cornered, press Spacebar or RMB to dodge to back or front plane.", []string{"never-switched"}},
+	{"plane-ways-out", "You still have two ways out.", []string{"never-switched"}},
+	{"eat-smaller", "Eat the fish smaller than you, or be eaten by a bigger fish.", []string{"dies-early"}},
+	{"not-afraid", "If the fish isn't afraid of you, that may be for a reason.", []string{"dies-early"}},
+	{"pause", "Press P or Enter to pause the game.", []string{"never-paused"}},
+	{"puffer-intro", "Pufferfish are easily scared and puff up to make themselves inedible... or more delicious.", []string{"new-to-puffer"}},
+	{"controls", "Use the keyboard or the mouse to move, whatever suits you best.", nil},
+	{"restart", "Press Spacebar to restart.", nil},
+	{"perspective", "Remember, objects further to the back are bigger than they appear.", nil},
+	{"options", "If you want more - or less - challenge, go to Options and play around.", nil},
+	{"greedy-score", "The bigger the fish, the better the score, but don't get too greedy.", nil},
+	{"hide-menu", "Press H or click the little arrow in the main menu to hide it and just relax.", nil},
+	{"goldfish-escape", "Goldfish will try to escape with a dodge and a dash.", nil},
+	{"jelly-brainless", "The jellyfish is brainless but not harmless. Still, just as edible as the other fish.", nil},
+	{"you-died", "YOU DIED", []string{"joke"}},
+	{"you-tried", "You tried.", []string{"joke"}},
+	{"fry-ed", "You're fry-ed.", []string{"joke"}},
+	{"try-again", "Try again.", []string{"joke"}},
+	{"lesson", "You've got a valuable lesson.", []string{"joke"}},
+	{"careful", "Be careful next time.", []string{"joke"}},
+	{"so-it-goes", "So it goes.", []string{"joke"}},
+	{"it-happens", "It happens.", []string{"joke"}},
+	{"too-greedy", "Too greedy.", []string{"joke"}},
+	{"belly-up", "You just went belly up.", []string{"joke"}},
+	{"indigestion", "Well, at least give them indigestion.", []string{"joke"}},
+	{"three-times", "Some fish can swallow prey 3 times their own size. Alas, you're not one of those.", []string{"joke"}},
+	{"bigger-fish", "There's always a bigger fish.", []string{"joke"}},
+	{"bite", "Don't bite more than you can swallow.", []string{"joke"}},
+	{"bill", "That's it, no more Bill, his greed got him killed.", []string{"joke"}},
+	{"oops", "Oops, someone got greedy.", []string{"joke"}},
+	{"gulp", "GULP", []string{"joke"}},
+}
+
+// tipConditions decide the tags; "killer:<species>" tags are checked against the death cause instead.
+var tipConditions = map[string]func(g *Game) bool{
+	"never-switched": func(g *Game) bool {
+		return g.planeCount > 1 && g.statistics.Lifetime.PlaneSwitches == 0
+	},
+	"dies-early": func(g *Game) bool {
+		life := &g.statistics.Lifetime
+		return life.Runs >= earlyDeathRuns && life.TicksAlive < earlyDeathTicks*life.Runs
+	},
+	"never-paused": func(g *Game) bool {
+		return !g.tips.Paused
+	},
+	"new-to-puffer": func(g *Game) bool {
+		life := &g.statistics.Lifetime
+		return life.EatenBySpecies["puffer"]+life.DeathsByKiller["puffer"] == 0
+	},
+	"joke": func(g *Game) bool {
+		return true
+	},
+}
+
+// TipProgress is what survives between sessions.
+type TipProgress struct {
+	Seen   map[string]int `json:"seen"`
+	Last   string         `json:"last"`
+	Paused bool           `json:"paused"`
+}
+
+func (g *Game) LoadTips() {
+	g.tips = TipProgress{Seen: map[string]int{}}
+	if err := loadConfig(tipsFile, &g.tips); err != nil {
+		log.Printf("tips: %v", err)
+	}
+}
+
+func (g *Game) TipApplies(tip Tip) bool {
+	for _, tag := range tip.Tags {
+		if species, ok := strings.CutPrefix(tag, "killer:"); ok {
+			if g.deathCause == nil || g.deathCause.Type != species {
+				return false
+			}
+		} else if !tipConditions[tag](g) {
+			return false
+		}
+	}
+	return true
+}
+
+// NextTip picks the least seen tip that applies, the most useful one on a tie, and never the previous one again.
+func (g *Game) NextTip() string {
+	best := -1
+	for i, tip := range tips {
+		if tip.ID == g.tips.Last || !g.TipApplies(tip) {
+			continue
+		}
+		if best < 0 || g.tips.Seen[tip.ID] < g.tips.Seen[tips[best].ID] {
+			best = i
+		}
+	}
+	tip := tips[best]
+	if g.TracksProgress(nil) {
+		g.tips.Seen[tip.ID]++
+		g.tips.Last = tip.ID
+		if err := saveConfig(tipsFile, g.tips); err != nil {
+			log.Printf("tips: %v", err)
+		}
+	}
+	return tip.Text
+}